@@ -0,0 +1,128 @@
+package calculator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+type attic struct{}
+
+// Attic (Greek acrophonic) numerals are purely additive, written from the
+// largest symbol down, from Ι up to 𐅇ΜΜΜΜ𐅆ΧΧΧΧ𐅅ΗΗΗΗ𐅄ΔΔΔΔΠΙΙΙΙ
+var Attic NumeralSystem = attic{}
+
+// Ordered from the largest value, the five symbols are used at most once and
+// the others at most four times in a row
+var atticSymbols = []struct {
+	symbol string
+	value  int
+	limit  int
+}{
+	{"𐅇", 50000, 1},
+	{"Μ", 10000, 4},
+	{"𐅆", 5000, 1},
+	{"Χ", 1000, 4},
+	{"𐅅", 500, 1},
+	{"Η", 100, 4},
+	{"𐅄", 50, 1},
+	{"Δ", 10, 4},
+	{"Π", 5, 1},
+	{"Ι", 1, 4},
+}
+
+const maxAtticValue = 99999
+
+func (attic) Name() string {
+	return "attic"
+}
+
+func (attic) IsSymbol(symbol string) bool {
+	return atticIndex(symbol) >= 0
+}
+
+func (a attic) Validate(symbols []string) error {
+	_, err := a.Parse(symbols)
+	return err
+}
+
+func (attic) Parse(symbols []string) (int, error) {
+	numeral := strings.Join(symbols, "")
+	total := 0
+	repeatCount := 0
+	prevIndex := -1
+
+	for i, symbol := range symbols {
+		index := atticIndex(symbol)
+		switch {
+		case index < 0:
+			return 0, &constant.InvalidNumeralError{
+				Numeral: numeral, Position: i + 1, Rule: fmt.Sprintf("%s is not an attic symbol", symbol),
+			}
+		case index < prevIndex:
+			return 0, &constant.InvalidNumeralError{
+				Numeral: numeral, Position: i + 1, Rule: "symbols are written from the largest value down",
+			}
+		case index == prevIndex:
+			repeatCount++
+		default:
+			repeatCount = 1
+		}
+		if repeatCount > atticSymbols[index].limit {
+			return 0, &constant.InvalidNumeralError{
+				Numeral:  numeral,
+				Position: i + 1,
+				Rule:     fmt.Sprintf("%s is repeated more than %d times", symbol, atticSymbols[index].limit),
+			}
+		}
+
+		total += atticSymbols[index].value
+		prevIndex = index
+	}
+
+	return total, nil
+}
+
+// Terms splits the symbols in runs of a symbol, whose values are added
+func (attic) Terms(symbols []string) []Term {
+	terms := make([]Term, 0)
+	for i := 0; i < len(symbols); {
+		end := i
+		for end < len(symbols) && symbols[end] == symbols[i] {
+			end++
+		}
+		value := 0
+		if index := atticIndex(symbols[i]); index >= 0 {
+			value = atticSymbols[index].value
+		}
+		terms = append(terms, sumTerm(strings.Join(symbols[i:end], ""), repeatValue(value, end-i)))
+		i = end
+	}
+	return terms
+}
+
+func (attic) Format(number int) ([]string, error) {
+	if number < 1 || number > maxAtticValue {
+		return nil, constant.ErrInvalidFormat
+	}
+
+	symbols := make([]string, 0)
+	for _, attic := range atticSymbols {
+		for number >= attic.value {
+			symbols = append(symbols, attic.symbol)
+			number -= attic.value
+		}
+	}
+
+	return symbols, nil
+}
+
+func atticIndex(symbol string) int {
+	for i, attic := range atticSymbols {
+		if attic.symbol == symbol {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,65 @@
+package calculator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+func TestAtticParse(t *testing.T) {
+	tests := []struct {
+		input    []string
+		expected int
+		position int
+	}{
+		{[]string{"Ι"}, 1, 0},
+		{[]string{"Δ", "Π", "Ι", "Ι"}, 17, 0},
+		{[]string{"Χ", "𐅅", "Η", "Η", "Η", "Η", "𐅄", "Δ", "Δ", "Δ", "Δ", "Ι", "Ι", "Ι", "Ι"}, 1994, 0},
+		{[]string{"𐅇", "Μ", "Μ", "Μ", "Μ"}, 90000, 0},
+		{[]string{"Ι", "Ι", "Ι", "Ι", "Ι"}, 0, 5},
+		{[]string{"Π", "Π"}, 0, 2},
+		{[]string{"Ι", "Δ"}, 0, 2},
+		{[]string{"Δ", "I"}, 0, 2},
+	}
+
+	for _, test := range tests {
+		result, err := Attic.Parse(test.input)
+		if test.position == 0 {
+			if err != nil || result != test.expected {
+				t.Errorf("For input %v, expected %d, got %d (%v)", test.input, test.expected, result, err)
+			}
+			continue
+		}
+
+		var numeralErr *constant.InvalidNumeralError
+		if !errors.As(err, &numeralErr) || numeralErr.Position != test.position {
+			t.Errorf("For input %v, expected error at %d, got %v", test.input, test.position, err)
+		}
+	}
+}
+
+func TestAtticFormat(t *testing.T) {
+	tests := []struct {
+		input    int
+		expected []string
+	}{
+		{4, []string{"Ι", "Ι", "Ι", "Ι"}},
+		{56, []string{"𐅄", "Π", "Ι"}},
+		{5500, []string{"𐅆", "𐅅"}},
+	}
+
+	for _, test := range tests {
+		result, err := Attic.Format(test.input)
+		if err != nil || !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("For input %d, expected %v, got %v (%v)", test.input, test.expected, result, err)
+		}
+	}
+
+	for _, input := range []int{0, 100000} {
+		if _, err := Attic.Format(input); !errors.Is(err, constant.ErrInvalidFormat) {
+			t.Errorf("Expected ErrInvalidFormat for input %d, got %v", input, err)
+		}
+	}
+}
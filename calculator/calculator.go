@@ -1,125 +1,168 @@
 package calculator
 
 import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
 	"github.com/erizkiatama/prospace-assignment/constant"
 	"github.com/erizkiatama/prospace-assignment/database"
-	"strings"
+	"github.com/erizkiatama/prospace-assignment/parser"
 )
 
 type Calculator interface {
 	ConvertUnitsToInt([]string) (int, error)
+	DeriveUnits([]string) (Derivation, error)
+	EvaluateExpression(parser.Expression) (int, error)
+	ConvertIntToUnits(number int, system string) ([]string, error)
 	CompareTwoUnits([]string, []string) (string, error)
-	CalculateCreditsCurrency(unitResult float32, currency string) (float32, error)
+	CalculateCreditsCurrency(unitResult *big.Rat, currency string) (*big.Rat, error)
+	CalculateCreditsCurrencyOn(unitResult *big.Rat, currency string, day time.Time) (*big.Rat, error)
 	CompareTwoCurrency([]string, []string, string, string) (string, error)
+	ConvertCurrency(quantity *big.Rat, from, to string) (*big.Rat, error)
+	CalculatePurchase(credits *big.Rat, currency string) (int, *big.Rat, *big.Rat, error)
+	RateFromSymbols(symbols []string, credits *big.Rat) (*big.Rat, error)
+	With(db database.Database) Calculator
 }
 
 type calculator struct {
-	db database.Database
+	db      database.Database
+	systems []NumeralSystem
 }
 
-var (
-	RomanValues = map[byte]int{
-		'I': 1,
-		'V': 5,
-		'X': 10,
-		'L': 50,
-		'C': 100,
-		'D': 500,
-		'M': 1000,
+// Option changes how a calculator reads numerals
+type Option func(*calculator)
+
+// WithLenientNumerals accepts historical Roman forms such as IIII and IIX,
+// which are rejected by default
+func WithLenientNumerals() Option {
+	return func(c *calculator) {
+		c.systems = []NumeralSystem{LenientRoman, Attic, Mayan}
 	}
+}
 
-	validSubtractions = map[byte][]byte{
-		'I': {'V', 'X'},
-		'X': {'L', 'C'},
-		'C': {'D', 'M'},
+func NewCalculator(db database.Database, options ...Option) Calculator {
+	c := &calculator{db: db, systems: NumeralSystems}
+	for _, option := range options {
+		option(c)
 	}
-)
+	return c
+}
 
-func NewCalculator(db database.Database) Calculator {
-	return &calculator{db: db}
+// With returns a calculator reading numerals as c does, from another
+// database, e.g. one read at once by database.ReadAtOnce
+func (c *calculator) With(db database.Database) Calculator {
+	return c.with(db)
 }
 
-func (c *calculator) convertUnitToRoman(units []string) (string, error) {
-	romanNumeral := ""
+func (c *calculator) with(db database.Database) *calculator {
+	return &calculator{db: db, systems: c.systems}
+}
+
+func (c *calculator) convertUnitsToSymbols(units []string) ([]string, error) {
+	symbols := make([]string, 0, len(units))
 	for _, unit := range units {
-		roman, err := c.db.GetRomanFromUnit(strings.ToLower(unit))
+		symbol, err := c.db.GetRomanFromUnit(strings.ToLower(unit))
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		romanNumeral += roman
+		symbols = append(symbols, symbol)
 	}
 
-	return romanNumeral, nil
+	return symbols, nil
 }
 
-func (c *calculator) convertRomanToInt(roman string) (int, error) {
-	total := 0
-	repeatCount := 0
-	prevValue := 0
-	prevSymbol := byte(0)
-
-	for i := len(roman) - 1; i >= 0; i-- {
-		currSymbol := roman[i]
-		currValue := RomanValues[currSymbol]
-
-		// Same roman symbol can only be repeated 3 times
-		if currSymbol == prevSymbol {
-			repeatCount++
-			if repeatCount > 3 || (currSymbol == 'D' || currSymbol == 'L' || currSymbol == 'V') {
-				return 0, constant.ErrInvalidFormat
-			}
-		} else {
-			repeatCount = 1
+func (c *calculator) convertSymbolsToUnits(symbols []string) ([]string, error) {
+	units := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		unit, err := c.db.GetUnitFromRoman(symbol)
+		if err != nil {
+			return nil, err
 		}
+		units = append(units, unit)
+	}
 
-		// A roman symbol can only be subtracted by specific roman symbols
-		if currValue >= prevValue {
-			total += currValue
-		} else {
-			if !isValidSubtraction(currSymbol, prevSymbol) {
-				return 0, constant.ErrInvalidFormat
-			}
-			total -= currValue
-		}
+	return units, nil
+}
 
-		prevValue = currValue
-		prevSymbol = currSymbol
+// ConvertUnitsToInt reads the units with the numeral system their symbols
+// belong to, so a set of units can not mix symbols of several systems.
+func (c *calculator) ConvertUnitsToInt(tokens []string) (int, error) {
+	symbols, err := c.convertUnitsToSymbols(tokens)
+	if err != nil {
+		return 0, err
+	}
+
+	system, err := numeralSystemOf(c.systems, symbols)
+	if err != nil {
+		return 0, err
 	}
 
-	return total, nil
+	return system.Parse(symbols)
 }
 
-func isValidSubtraction(smaller, larger byte) bool {
-	allowed, exists := validSubtractions[smaller]
-	if !exists {
-		return false
+// ConvertIntToUnits writes a number with the named numeral system, an empty
+// name being Roman.
+func (c *calculator) ConvertIntToUnits(number int, systemName string) ([]string, error) {
+	system, err := NumeralSystemByName(systemName)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, symbol := range allowed {
-		if symbol == larger {
-			return true
-		}
+	symbols, err := system.Format(number)
+	if err != nil {
+		return nil, err
 	}
 
-	return false
+	return c.convertSymbolsToUnits(symbols)
 }
 
-func (c *calculator) ConvertUnitsToInt(tokens []string) (int, error) {
-	romanNumeral, err := c.convertUnitToRoman(tokens)
+// RateFromSymbols returns the credits of a single currency unit, given the
+// symbols the units of the quantity are assigned to and the total credits paid
+// for it. It does not use the database, so it is the database.RateFunc rates
+// are defined and recalculated with.
+func (c *calculator) RateFromSymbols(symbols []string, credits *big.Rat) (*big.Rat, error) {
+	system, err := numeralSystemOf(c.systems, symbols)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	unitResult, err := system.Parse(symbols)
+	if err != nil {
+		return nil, err
+	}
+	if unitResult == 0 {
+		return nil, constant.ErrInvalidFormat
 	}
 
-	return c.convertRomanToInt(romanNumeral)
+	return new(big.Rat).Quo(credits, big.NewRat(int64(unitResult), 1)), nil
 }
 
-func (c *calculator) CalculateCreditsCurrency(unitResult float32, currency string) (float32, error) {
+func (c *calculator) CalculateCreditsCurrency(unitResult *big.Rat, currency string) (*big.Rat, error) {
 	credits, err := c.db.GetCreditsFromCurrency(strings.ToLower(currency))
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	return credits.Mul(credits, unitResult), nil
+}
+
+// CalculateCreditsCurrencyOn is CalculateCreditsCurrency with the rate the
+// currency had on a day, the last one set on that day
+func (c *calculator) CalculateCreditsCurrencyOn(unitResult *big.Rat, currency string, day time.Time) (*big.Rat, error) {
+	credits, err := c.db.GetCreditsFromCurrencyAt(strings.ToLower(currency), EndOfDay(day))
+	if err != nil {
+		return nil, err
 	}
 
-	return unitResult * credits, nil
+	return credits.Mul(credits, unitResult), nil
+}
+
+// EndOfDay is the last instant of the day, the rate of a day is the one it
+// ends with
+func EndOfDay(day time.Time) time.Time {
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond)
 }
 
 func (c *calculator) getUnitResults(first, second []string) (int, int, error) {
@@ -150,26 +193,70 @@ func (c *calculator) CompareTwoUnits(firstUnits, secondUnits []string) (string,
 	return "equal to", nil
 }
 
+// CompareTwoCurrency reads the units and both rates at once, so a unit or a
+// rate changed meanwhile can not be seen on one side only
 func (c *calculator) CompareTwoCurrency(firstUnits, secondUnits []string, firstCurrency, secondCurrency string) (string, error) {
-	firstUnitResult, secondUnitResult, err := c.getUnitResults(firstUnits, secondUnits)
-	if err != nil {
-		return "", err
-	}
+	units := append(append([]string{}, firstUnits...), secondUnits...)
+	view := c.with(database.ReadAtOnce(c.db, units, []string{firstCurrency, secondCurrency}))
 
-	firstResult, err := c.CalculateCreditsCurrency(float32(firstUnitResult), firstCurrency)
+	firstUnitResult, secondUnitResult, err := view.getUnitResults(firstUnits, secondUnits)
 	if err != nil {
 		return "", err
 	}
 
-	secondResult, err := c.CalculateCreditsCurrency(float32(secondUnitResult), secondCurrency)
+	rates, err := view.db.GetCreditsFromCurrencies(strings.ToLower(firstCurrency), strings.ToLower(secondCurrency))
 	if err != nil {
 		return "", err
 	}
 
-	if firstResult > secondResult {
+	firstResult := rates[0].Mul(rates[0], big.NewRat(int64(firstUnitResult), 1))
+	secondResult := rates[1].Mul(rates[1], big.NewRat(int64(secondUnitResult), 1))
+
+	switch firstResult.Cmp(secondResult) {
+	case 1:
 		return "has more credits than", nil
-	} else if firstResult < secondResult {
+	case -1:
 		return "has less credits than", nil
 	}
 	return "has equal credits with", nil
 }
+
+// ConvertCurrency returns how much of the to currency is worth the quantity
+// of the from currency, going through their credits.
+func (c *calculator) ConvertCurrency(quantity *big.Rat, from, to string) (*big.Rat, error) {
+	rates, err := c.db.GetCreditsFromCurrencies(strings.ToLower(from), strings.ToLower(to))
+	if err != nil {
+		return nil, err
+	}
+
+	credits, toCredits := rates[0].Mul(rates[0], quantity), rates[1]
+	if toCredits.Sign() == 0 {
+		return nil, &constant.ZeroRateError{Currency: strings.ToLower(to)}
+	}
+
+	return credits.Quo(credits, toCredits), nil
+}
+
+// CalculatePurchase returns how many whole units of the currency the credits
+// buy, the credits left after buying them, and the rate they were bought at
+func (c *calculator) CalculatePurchase(credits *big.Rat, currency string) (int, *big.Rat, *big.Rat, error) {
+	rate, err := c.db.GetCreditsFromCurrency(strings.ToLower(currency))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if rate.Sign() == 0 {
+		return 0, nil, nil, &constant.ZeroRateError{Currency: strings.ToLower(currency)}
+	}
+
+	ratio := new(big.Rat).Quo(credits, rate)
+	quantity := new(big.Int).Quo(ratio.Num(), ratio.Denom())
+	if quantity.Cmp(big.NewInt(math.MaxInt)) > 0 {
+		return 0, nil, nil, &constant.ArithmeticError{
+			Operation: fmt.Sprintf("%s divided by %s", credits.RatString(), rate.RatString()),
+			Reason:    "the result is too large",
+		}
+	}
+
+	spent := new(big.Rat).Mul(new(big.Rat).SetInt(quantity), rate)
+	return int(quantity.Int64()), new(big.Rat).Sub(credits, spent), rate, nil
+}
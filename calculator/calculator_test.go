@@ -2,19 +2,33 @@ package calculator
 
 import (
 	"errors"
+	"math/big"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+	"github.com/erizkiatama/prospace-assignment/database"
 )
 
 // Mock database for testing
 type mockDB struct {
 	unitToRoman       map[string]string
-	currencyToCredits map[string]float32
+	currencyToCredits map[string]*big.Rat
+	sources           map[string]database.RateSource
+	history           map[string][]database.RatePoint
 }
 
-func (m *mockDB) AddUnitToRomanMapping(unit, roman string) {
+// setUnit and setRate fill the mock without going through a rate
+func (m *mockDB) setUnit(unit, roman string) {
 	m.unitToRoman[unit] = roman
 }
 
+func (m *mockDB) setRate(currency string, credits *big.Rat) {
+	m.currencyToCredits[currency] = credits
+}
+
 func (m *mockDB) GetRomanFromUnit(unit string) (string, error) {
 	if roman, ok := m.unitToRoman[unit]; ok {
 		return roman, nil
@@ -22,31 +36,149 @@ func (m *mockDB) GetRomanFromUnit(unit string) (string, error) {
 	return "", errors.New("unit not found")
 }
 
-func (m *mockDB) AddCurrencyToCreditsMapping(currency string, credits float32) {
-	m.currencyToCredits[currency] = credits
+func (m *mockDB) GetUnitFromRoman(roman string) (string, error) {
+	for unit, value := range m.unitToRoman {
+		if value == roman {
+			return unit, nil
+		}
+	}
+	return "", errors.New("roman not found")
 }
 
-func (m *mockDB) GetCreditsFromCurrency(currency string) (float32, error) {
+func (m *mockDB) GetCreditsFromCurrency(currency string) (*big.Rat, error) {
 	if credits, ok := m.currencyToCredits[currency]; ok {
-		return credits, nil
+		return new(big.Rat).Set(credits), nil
+	}
+	return nil, errors.New("currency not found")
+}
+
+func (m *mockDB) GetCreditsFromCurrencies(currencies ...string) ([]*big.Rat, error) {
+	rates := make([]*big.Rat, len(currencies))
+	for i, currency := range currencies {
+		credits, err := m.GetCreditsFromCurrency(currency)
+		if err != nil {
+			return nil, err
+		}
+		rates[i] = credits
+	}
+	return rates, nil
+}
+
+func (m *mockDB) Read(units, currencies []string) database.Reading {
+	reading := database.Reading{
+		Symbols: make(map[string]string),
+		Rates:   make(map[string]*big.Rat),
+		Sources: make(map[string]database.RateSource),
+	}
+	for _, unit := range units {
+		if roman, err := m.GetRomanFromUnit(unit); err == nil {
+			reading.Symbols[unit] = roman
+		}
+	}
+	for _, currency := range currencies {
+		if credits, err := m.GetCreditsFromCurrency(currency); err == nil {
+			reading.Rates[currency] = credits
+		}
+		if source, exists := m.sources[currency]; exists {
+			reading.Sources[currency] = source
+		}
+	}
+	return reading
+}
+
+func (m *mockDB) RemoveCurrency(currency string) error {
+	delete(m.currencyToCredits, currency)
+	return nil
+}
+
+func (m *mockDB) DefineUnit(unit, roman string, rate database.RateFunc) ([]string, error) {
+	m.setUnit(unit, roman)
+	return []string{}, nil
+}
+
+func (m *mockDB) ForgetUnit(unit string, rate database.RateFunc) ([]string, error) {
+	delete(m.unitToRoman, unit)
+	return []string{}, nil
+}
+
+func (m *mockDB) DefineRate(currency string, source database.RateSource, rate database.RateFunc) (*big.Rat, error) {
+	symbols := make([]string, len(source.Units))
+	for i, unit := range source.Units {
+		symbols[i] = m.unitToRoman[unit]
+	}
+	credits, err := rate(symbols, source.Credits)
+	if err != nil {
+		return nil, err
+	}
+	m.setRate(currency, credits)
+	m.sources[currency] = source
+	return credits, nil
+}
+
+func (m *mockDB) GetRateSource(currency string) (database.RateSource, error) {
+	if source, ok := m.sources[currency]; ok {
+		return source, nil
+	}
+	return database.RateSource{}, errors.New("currency not found")
+}
+
+func (m *mockDB) GetCreditsFromCurrencyAt(currency string, at time.Time) (*big.Rat, error) {
+	history := m.history[currency]
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].Time.After(at) {
+			return new(big.Rat).Set(history[i].Credits), nil
+		}
+	}
+	return nil, errors.New("rate not found")
+}
+
+func (m *mockDB) GetRateHistory(currency string) ([]database.RatePoint, error) {
+	if history, ok := m.history[currency]; ok {
+		return history, nil
+	}
+	return nil, errors.New("currency not found")
+}
+
+func (m *mockDB) ListUnits() []string {
+	units := make([]string, 0, len(m.unitToRoman))
+	for unit := range m.unitToRoman {
+		units = append(units, unit)
 	}
-	return 0, errors.New("currency not found")
+	return units
 }
 
+func (m *mockDB) ListCurrencies() []string {
+	currencies := make([]string, 0, len(m.currencyToCredits))
+	for currency := range m.currencyToCredits {
+		currencies = append(currencies, currency)
+	}
+	return currencies
+}
+
+func (m *mockDB) Snapshot(scope database.Scope) database.Snapshot { return database.Snapshot{} }
+
+func (m *mockDB) Restore(snapshot database.Snapshot) error { return nil }
+
 func newMockDatabase() *mockDB {
 	return &mockDB{
 		unitToRoman:       make(map[string]string),
-		currencyToCredits: make(map[string]float32),
+		currencyToCredits: make(map[string]*big.Rat),
+		sources:           make(map[string]database.RateSource),
+		history:           make(map[string][]database.RatePoint),
 	}
 }
 
 func TestConvertUnitsToInt(t *testing.T) {
 	mockDB := newMockDatabase()
-	mockDB.AddUnitToRomanMapping("xyz", "I")
-	mockDB.AddUnitToRomanMapping("abc", "V")
-	mockDB.AddUnitToRomanMapping("def", "X")
-	mockDB.AddUnitToRomanMapping("jkl", "L")
-	mockDB.AddUnitToRomanMapping("rst", "M")
+	mockDB.setUnit("xyz", "I")
+	mockDB.setUnit("abc", "V")
+	mockDB.setUnit("def", "X")
+	mockDB.setUnit("jkl", "L")
+	mockDB.setUnit("rst", "M")
+	mockDB.setUnit("uno", "1")
+	mockDB.setUnit("cero", "0")
+	mockDB.setUnit("delta", "Δ")
+	mockDB.setUnit("pente", "Π")
 
 	calc := NewCalculator(mockDB)
 
@@ -56,6 +188,11 @@ func TestConvertUnitsToInt(t *testing.T) {
 		hasError bool
 	}{
 		{[]string{"xyz", "abc"}, 4, false},
+		{[]string{"uno", "cero", "uno"}, 401, false},
+		{[]string{"delta", "pente"}, 15, false},
+		{[]string{"pente", "delta"}, 0, true},
+		{[]string{"xyz", "delta"}, 0, true},
+		{[]string{"uno", "xyz"}, 0, true},
 		{[]string{"def", "jkl", "xyz", "xyz"}, 42, false},
 		{[]string{"xyz", "xyz", "xyz", "xyz", "xyz"}, 0, true},
 		{[]string{"jkl", "rst"}, 0, true},
@@ -77,22 +214,96 @@ func TestConvertUnitsToInt(t *testing.T) {
 	}
 }
 
+func TestConvertUnitsToIntLenient(t *testing.T) {
+	mockDB := newMockDatabase()
+	mockDB.setUnit("xyz", "I")
+	mockDB.setUnit("def", "X")
+
+	strict := NewCalculator(mockDB)
+	lenient := NewCalculator(mockDB, WithLenientNumerals())
+
+	tests := []struct {
+		input    []string
+		expected int
+	}{
+		{[]string{"xyz", "xyz", "xyz", "xyz"}, 4},
+		{[]string{"xyz", "xyz", "def"}, 8},
+	}
+
+	for _, test := range tests {
+		if _, err := strict.ConvertUnitsToInt(test.input); err == nil {
+			t.Errorf("Expected strict error for input %v, got none", test.input)
+		}
+		result, err := lenient.ConvertUnitsToInt(test.input)
+		if err != nil || result != test.expected {
+			t.Errorf("For input %v, expected %d, got %d (%v)", test.input, test.expected, result, err)
+		}
+	}
+}
+
+func TestConvertIntToUnits(t *testing.T) {
+	mockDB := newMockDatabase()
+	mockDB.setUnit("xyz", "I")
+	mockDB.setUnit("abc", "V")
+	mockDB.setUnit("def", "X")
+	mockDB.setUnit("jkl", "L")
+	mockDB.setUnit("ghi", "C")
+	mockDB.setUnit("uno", "1")
+	mockDB.setUnit("dos", "2")
+	mockDB.setUnit("delta", "Δ")
+
+	calc := NewCalculator(mockDB)
+
+	tests := []struct {
+		input    int
+		system   string
+		expected []string
+		hasError bool
+	}{
+		{4, "", []string{"xyz", "abc"}, false},
+		{42, "roman", []string{"def", "jkl", "xyz", "xyz"}, false},
+		{99, "", []string{"def", "ghi", "xyz", "def"}, false},
+		{1944, "", nil, true},
+		{0, "", nil, true},
+		{4000, "", nil, true},
+		{41, "mayan", []string{"dos", "uno"}, false},
+		{20, "Attic", []string{"delta", "delta"}, false},
+		{21, "attic", nil, true},
+		{5, "babylonian", nil, true},
+	}
+
+	for _, test := range tests {
+		result, err := calc.ConvertIntToUnits(test.input, test.system)
+		if test.hasError && err == nil {
+			t.Errorf("Expected error for input %d, got none", test.input)
+		}
+		if !test.hasError && err != nil {
+			t.Errorf("Unexpected error for input %d: %v", test.input, err)
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("For input %d, expected %v, got %v", test.input, test.expected, result)
+		}
+	}
+}
+
 func TestCalculateCreditsCurrency(t *testing.T) {
 	mockDB := newMockDatabase()
-	mockDB.AddCurrencyToCreditsMapping("gold", 14450.0)
-	mockDB.AddCurrencyToCreditsMapping("silver", 17.0)
+	mockDB.setRate("gold", big.NewRat(14450, 1))
+	mockDB.setRate("silver", big.NewRat(17, 1))
+	mockDB.setRate("iron", big.NewRat(391, 2))
 
 	calc := NewCalculator(mockDB)
 
 	tests := []struct {
-		unitResult float32
+		unitResult *big.Rat
 		currency   string
-		expected   float32
+		expected   string
 		hasError   bool
 	}{
-		{2, "gold", 28900.0, false},
-		{3, "silver", 51.0, false},
-		{1, "unknown", 0, true},
+		{big.NewRat(2, 1), "gold", "28900", false},
+		{big.NewRat(3, 1), "silver", "51", false},
+		{big.NewRat(41, 1), "iron", "16031/2", false},
+		{big.NewRat(1, 1), "unknown", "", true},
 	}
 
 	for _, test := range tests {
@@ -103,17 +314,58 @@ func TestCalculateCreditsCurrency(t *testing.T) {
 		if !test.hasError && err != nil {
 			t.Errorf("Unexpected error for input %v %s: %v", test.unitResult, test.currency, err)
 		}
-		if result != test.expected {
-			t.Errorf("For input %v %s, expected %f, got %f", test.unitResult, test.currency, test.expected, result)
+		if result != nil && result.RatString() != test.expected {
+			t.Errorf("For input %v %s, expected %s, got %s", test.unitResult, test.currency, test.expected, result.RatString())
+		}
+	}
+}
+
+func TestCalculateCreditsCurrencyOn(t *testing.T) {
+	january := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mockDB := newMockDatabase()
+	mockDB.history["gold"] = []database.RatePoint{
+		{Time: january, Credits: big.NewRat(14450, 1)},
+		{Time: march.Add(12 * time.Hour), Credits: big.NewRat(15000, 1)},
+	}
+
+	calc := NewCalculator(mockDB)
+
+	tests := []struct {
+		unitResult *big.Rat
+		currency   string
+		day        time.Time
+		expected   string
+		hasError   bool
+	}{
+		{big.NewRat(2, 1), "gold", january, "28900", false},
+		{big.NewRat(2, 1), "Gold", march.AddDate(0, 0, -1), "28900", false},
+		// The rate set at noon is the one the day ends with
+		{big.NewRat(2, 1), "gold", march, "30000", false},
+		{big.NewRat(2, 1), "gold", january.AddDate(0, 0, -1), "", true},
+		{big.NewRat(1, 1), "unknown", march, "", true},
+	}
+
+	for _, test := range tests {
+		result, err := calc.CalculateCreditsCurrencyOn(test.unitResult, test.currency, test.day)
+		if test.hasError && err == nil {
+			t.Errorf("Expected error for input %v %s on %v, got none", test.unitResult, test.currency, test.day)
+		}
+		if !test.hasError && err != nil {
+			t.Errorf("Unexpected error for input %v %s on %v: %v", test.unitResult, test.currency, test.day, err)
+		}
+		if result != nil && result.RatString() != test.expected {
+			t.Errorf("For input %v %s on %v, expected %s, got %s", test.unitResult, test.currency, test.day, test.expected, result.RatString())
 		}
 	}
 }
 
 func TestCompareTwoUnits(t *testing.T) {
 	mockDB := newMockDatabase()
-	mockDB.AddUnitToRomanMapping("xyz", "I")
-	mockDB.AddUnitToRomanMapping("abc", "V")
-	mockDB.AddUnitToRomanMapping("def", "X")
+	mockDB.setUnit("xyz", "I")
+	mockDB.setUnit("abc", "V")
+	mockDB.setUnit("def", "X")
 
 	calc := NewCalculator(mockDB)
 
@@ -146,10 +398,13 @@ func TestCompareTwoUnits(t *testing.T) {
 
 func TestCompareTwoCurrency(t *testing.T) {
 	mockDB := newMockDatabase()
-	mockDB.AddUnitToRomanMapping("xyz", "I")
-	mockDB.AddUnitToRomanMapping("abc", "V")
-	mockDB.AddCurrencyToCreditsMapping("gold", 14450.0)
-	mockDB.AddCurrencyToCreditsMapping("silver", 17.0)
+	mockDB.setUnit("xyz", "I")
+	mockDB.setUnit("abc", "V")
+	mockDB.setUnit("def", "X")
+	mockDB.setRate("gold", big.NewRat(14450, 1))
+	mockDB.setRate("silver", big.NewRat(17, 1))
+	mockDB.setRate("copper", big.NewRat(1, 10))
+	mockDB.setRate("tin", big.NewRat(3, 10))
 
 	calc := NewCalculator(mockDB)
 
@@ -164,6 +419,9 @@ func TestCompareTwoCurrency(t *testing.T) {
 		{[]string{"xyz", "abc"}, []string{"xyz"}, "gold", "silver", "has more credits than", false},
 		{[]string{"xyz"}, []string{"xyz", "abc"}, "silver", "gold", "has less credits than", false},
 		{[]string{"xyz"}, []string{"xyz"}, "gold", "gold", "has equal credits with", false},
+		{[]string{"xyz", "xyz", "xyz"}, []string{"xyz"}, "copper", "tin", "has equal credits with", false},
+		{[]string{"xyz", "xyz"}, []string{"xyz"}, "copper", "tin", "has less credits than", false},
+		{[]string{"def"}, []string{"xyz", "xyz", "xyz"}, "copper", "tin", "has more credits than", false},
 		{[]string{"unknown"}, []string{"xyz"}, "gold", "silver", "", true},
 		{[]string{"xyz"}, []string{"xyz"}, "unknown", "gold", "", true},
 		{[]string{"xyz"}, []string{"xyz"}, "gold", "unknown", "", true},
@@ -182,3 +440,171 @@ func TestCompareTwoCurrency(t *testing.T) {
 		}
 	}
 }
+
+func TestCompareTwoCurrencyWhileRedefined(t *testing.T) {
+	db := database.NewDatabase(database.WithPolicy(database.PolicyOverwrite))
+	calc := NewCalculator(db)
+	db.DefineUnit("tegj", "I", calc.RateFromSymbols)
+	db.DefineUnit("glob", "I", calc.RateFromSymbols)
+	db.DefineRate("silver", database.RateSource{Units: []string{"tegj"}, Credits: big.NewRat(10, 1)}, calc.RateFromSymbols)
+	db.DefineRate("gold", database.RateSource{Units: []string{"glob"}, Credits: big.NewRat(10, 1)}, calc.RateFromSymbols)
+
+	const rounds = 2000
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// The rate of gold follows glob, glob gold stays worth 10 Credits
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				db.DefineUnit("glob", "IV"[i%2:i%2+1], calc.RateFromSymbols)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < rounds; i++ {
+			result, err := calc.CompareTwoCurrency([]string{"glob"}, []string{"tegj"}, "gold", "silver")
+			if err != nil || result != "has equal credits with" {
+				t.Errorf("Expected glob gold to equal tegj silver, got %q (%v)", result, err)
+				return
+			}
+
+			view := calc.With(database.ReadAtOnce(db, []string{"glob"}, []string{"gold"}))
+			value, err := view.ConvertUnitsToInt([]string{"glob"})
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			credits, err := view.CalculateCreditsCurrency(big.NewRat(int64(value), 1), "gold")
+			if err != nil || credits.Cmp(big.NewRat(10, 1)) != 0 {
+				t.Errorf("Expected glob gold to be 10 Credits, got %v (%v)", credits, err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestConvertCurrency(t *testing.T) {
+	mockDB := newMockDatabase()
+	mockDB.setRate("gold", big.NewRat(14450, 1))
+	mockDB.setRate("silver", big.NewRat(17, 1))
+	mockDB.setRate("dust", big.NewRat(0, 1))
+
+	calc := NewCalculator(mockDB)
+
+	tests := []struct {
+		quantity *big.Rat
+		from     string
+		to       string
+		expected string
+		hasError bool
+	}{
+		{big.NewRat(1, 1), "gold", "silver", "850", false},
+		{big.NewRat(10, 1), "silver", "gold", "1/85", false},
+		{big.NewRat(21, 2), "silver", "silver", "21/2", false},
+		{big.NewRat(1, 1), "unknown", "gold", "", true},
+		{big.NewRat(1, 1), "gold", "unknown", "", true},
+		{big.NewRat(1, 1), "gold", "dust", "", true},
+	}
+
+	for _, test := range tests {
+		result, err := calc.ConvertCurrency(test.quantity, test.from, test.to)
+		if test.hasError && err == nil {
+			t.Errorf("Expected error for input %v %s to %s, got none", test.quantity, test.from, test.to)
+		}
+		if !test.hasError && err != nil {
+			t.Errorf("Unexpected error for input %v %s to %s: %v", test.quantity, test.from, test.to, err)
+		}
+		if result != nil && result.RatString() != test.expected {
+			t.Errorf("For input %v %s to %s, expected %s, got %s", test.quantity, test.from, test.to, test.expected, result.RatString())
+		}
+	}
+
+	var zeroRate *constant.ZeroRateError
+	if _, err := calc.ConvertCurrency(big.NewRat(1, 1), "gold", "Dust"); !errors.As(err, &zeroRate) || zeroRate.Currency != "dust" {
+		t.Errorf("ConvertCurrency() to a currency worth 0 Credits error = %v, want a *ZeroRateError for dust", err)
+	}
+}
+
+func TestCalculatePurchase(t *testing.T) {
+	mockDB := newMockDatabase()
+	mockDB.setRate("silver", big.NewRat(17, 1))
+	mockDB.setRate("iron", big.NewRat(391, 2))
+	mockDB.setRate("dust", big.NewRat(0, 1))
+
+	calc := NewCalculator(mockDB)
+
+	tests := []struct {
+		credits   *big.Rat
+		currency  string
+		quantity  int
+		remainder string
+		rate      string
+		hasError  bool
+	}{
+		{big.NewRat(500, 1), "Silver", 29, "7", "17", false},
+		{big.NewRat(34, 1), "silver", 2, "0", "17", false},
+		{big.NewRat(10, 1), "silver", 0, "10", "17", false},
+		{big.NewRat(1000, 1), "iron", 5, "45/2", "391/2", false},
+		{big.NewRat(1, 1), "unknown", 0, "", "", true},
+		{big.NewRat(1, 1), "dust", 0, "", "", true},
+	}
+
+	for _, test := range tests {
+		quantity, remainder, rate, err := calc.CalculatePurchase(test.credits, test.currency)
+		if test.hasError && err == nil {
+			t.Errorf("Expected error for input %v Credits of %s, got none", test.credits, test.currency)
+		}
+		if !test.hasError && err != nil {
+			t.Errorf("Unexpected error for input %v Credits of %s: %v", test.credits, test.currency, err)
+		}
+		if quantity != test.quantity || (remainder != nil && remainder.RatString() != test.remainder) {
+			t.Errorf("For input %v Credits of %s, expected %d and %s left, got %d and %v", test.credits, test.currency, test.quantity, test.remainder, quantity, remainder)
+		}
+		if rate != nil && rate.RatString() != test.rate {
+			t.Errorf("For input %v Credits of %s, expected a rate of %s, got %v", test.credits, test.currency, test.rate, rate)
+		}
+	}
+
+	var zeroRate *constant.ZeroRateError
+	if _, _, _, err := calc.CalculatePurchase(big.NewRat(1, 1), "Dust"); !errors.As(err, &zeroRate) || zeroRate.Currency != "dust" {
+		t.Errorf("CalculatePurchase() of a currency worth 0 Credits error = %v, want a *ZeroRateError for dust", err)
+	}
+}
+
+func TestRateFromSymbols(t *testing.T) {
+	calc := NewCalculator(newMockDatabase())
+
+	tests := []struct {
+		symbols  []string
+		credits  *big.Rat
+		expected string
+		hasError bool
+	}{
+		{[]string{"I", "I"}, big.NewRat(34, 1), "17", false},
+		{[]string{"I", "V"}, big.NewRat(782, 1), "391/2", false},
+		{[]string{"V", "V"}, big.NewRat(1, 1), "", true},
+		{[]string{"I", "I", "I", "I"}, big.NewRat(1, 1), "", true},
+		{[]string{}, big.NewRat(34, 1), "", true},
+	}
+
+	for _, test := range tests {
+		result, err := calc.RateFromSymbols(test.symbols, test.credits)
+		if test.hasError && err == nil {
+			t.Errorf("Expected error for input %v %v, got none", test.symbols, test.credits)
+		}
+		if !test.hasError && err != nil {
+			t.Errorf("Unexpected error for input %v %v: %v", test.symbols, test.credits, err)
+		}
+		if result != nil && result.RatString() != test.expected {
+			t.Errorf("For input %v %v, expected %s, got %s", test.symbols, test.credits, test.expected, result.RatString())
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package calculator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Derivation is how units were read as a number, step by step
+type Derivation struct {
+	Units   []string
+	Symbols []string
+	System  string
+	// Terms are the parts the number is the sum of
+	Terms []Term
+	Value int
+}
+
+// Sum writes the addition of the terms, empty when there is a single term
+func (d Derivation) Sum() string {
+	if len(d.Terms) < 2 {
+		return ""
+	}
+
+	values := make([]string, len(d.Terms))
+	for i, term := range d.Terms {
+		values[i] = strconv.Itoa(term.Value)
+	}
+	return fmt.Sprintf("%s = %d", strings.Join(values, " + "), d.Value)
+}
+
+// DeriveUnits reads the units like ConvertUnitsToInt, keeping the symbols
+// assigned to them and the terms of their value
+func (c *calculator) DeriveUnits(units []string) (Derivation, error) {
+	symbols, err := c.convertUnitsToSymbols(units)
+	if err != nil {
+		return Derivation{}, err
+	}
+
+	system, err := numeralSystemOf(c.systems, symbols)
+	if err != nil {
+		return Derivation{}, err
+	}
+
+	value, err := system.Parse(symbols)
+	if err != nil {
+		return Derivation{}, err
+	}
+
+	return Derivation{
+		Units:   units,
+		Symbols: symbols,
+		System:  system.Name(),
+		Terms:   system.Terms(symbols),
+		Value:   value,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package calculator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeriveUnits(t *testing.T) {
+	mockDB := newMockDatabase()
+	mockDB.setUnit("glob", "I")
+	mockDB.setUnit("pish", "X")
+	mockDB.setUnit("tegj", "L")
+	mockDB.setUnit("uno", "1")
+
+	calc := NewCalculator(mockDB)
+
+	tests := []struct {
+		input    []string
+		expected Derivation
+		sum      string
+		hasError bool
+	}{
+		{
+			input: []string{"pish", "tegj", "glob", "glob"},
+			expected: Derivation{
+				Units:   []string{"pish", "tegj", "glob", "glob"},
+				Symbols: []string{"X", "L", "I", "I"},
+				System:  "roman",
+				Terms:   []Term{{"XL = 50 - 10 = 40", 40}, {"II = 1 + 1 = 2", 2}},
+				Value:   42,
+			},
+			sum: "40 + 2 = 42",
+		},
+		{
+			input: []string{"uno", "uno"},
+			expected: Derivation{
+				Units:   []string{"uno", "uno"},
+				Symbols: []string{"1", "1"},
+				System:  "mayan",
+				Terms:   []Term{{"1 = 1 × 20 = 20", 20}, {"1 = 1 × 1 = 1", 1}},
+				Value:   21,
+			},
+			sum: "20 + 1 = 21",
+		},
+		{
+			input: []string{"glob"},
+			expected: Derivation{
+				Units: []string{"glob"}, Symbols: []string{"I"}, System: "roman", Terms: []Term{{"I = 1", 1}}, Value: 1,
+			},
+		},
+		{input: []string{"glob", "wood"}, hasError: true},
+		{input: []string{"glob", "glob", "glob", "glob"}, hasError: true},
+	}
+
+	for _, test := range tests {
+		result, err := calc.DeriveUnits(test.input)
+		if test.hasError {
+			if err == nil {
+				t.Errorf("Expected error for input %v, got none", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for input %v: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("DeriveUnits(%v) = %+v, want %+v", test.input, result, test.expected)
+		}
+		if sum := result.Sum(); sum != test.sum {
+			t.Errorf("Sum() = %q, want %q", sum, test.sum)
+		}
+	}
+}
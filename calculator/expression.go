@@ -0,0 +1,64 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+	"github.com/erizkiatama/prospace-assignment/parser"
+)
+
+// EvaluateExpression computes an expression on the values of its units, every
+// operand being read like ConvertUnitsToInt
+func (c *calculator) EvaluateExpression(expr parser.Expression) (int, error) {
+	switch expr := expr.(type) {
+	case parser.Operand:
+		return c.ConvertUnitsToInt(expr.Units)
+	case parser.Operation:
+		left, err := c.EvaluateExpression(expr.Left)
+		if err != nil {
+			return 0, err
+		}
+		right, err := c.EvaluateExpression(expr.Right)
+		if err != nil {
+			return 0, err
+		}
+		return Operate(expr.Operator, left, right)
+	}
+	return 0, fmt.Errorf("unsupported expression %T", expr)
+}
+
+// Operate applies an operator of an expression to two whole numbers. The
+// result has to be a whole number too, so a division has to be exact.
+func Operate(operator string, left, right int) (int, error) {
+	x, y := big.NewInt(int64(left)), big.NewInt(int64(right))
+	result := new(big.Int)
+	switch operator {
+	case parser.Plus:
+		result.Add(x, y)
+	case parser.Minus:
+		result.Sub(x, y)
+	case parser.Times:
+		result.Mul(x, y)
+	case parser.Divided:
+		if right == 0 {
+			return 0, arithmeticError(operator, left, right, "a number can not be divided by zero")
+		}
+		remainder := new(big.Int)
+		if result.QuoRem(x, y, remainder); remainder.Sign() != 0 {
+			return 0, arithmeticError(operator, left, right, "the result is not a whole number")
+		}
+	default:
+		return 0, fmt.Errorf("unsupported operator %q", operator)
+	}
+
+	if result.Cmp(big.NewInt(math.MaxInt)) > 0 || result.Cmp(big.NewInt(math.MinInt)) < 0 {
+		return 0, arithmeticError(operator, left, right, "the result is too large")
+	}
+	return int(result.Int64()), nil
+}
+
+func arithmeticError(operator string, left, right int, reason string) error {
+	return &constant.ArithmeticError{Operation: fmt.Sprintf("%d %s %d", left, operator, right), Reason: reason}
+}
@@ -0,0 +1,84 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+	"github.com/erizkiatama/prospace-assignment/parser"
+)
+
+func TestEvaluateExpression(t *testing.T) {
+	mockDB := newMockDatabase()
+	mockDB.setUnit("glob", "I")
+	mockDB.setUnit("prok", "V")
+	mockDB.setUnit("pish", "X")
+
+	calc := NewCalculator(mockDB)
+	glob, prok, pish := parser.Operand{Units: []string{"glob"}}, parser.Operand{Units: []string{"prok"}}, parser.Operand{Units: []string{"pish"}}
+
+	tests := []struct {
+		input    parser.Expression
+		expected int
+		hasError bool
+	}{
+		{parser.Operand{Units: []string{"pish", "glob", "glob"}}, 12, false},
+		{parser.Operation{Operator: parser.Plus, Left: pish, Right: prok}, 15, false},
+		{parser.Operation{Operator: parser.Minus, Left: glob, Right: pish}, -9, false},
+		{parser.Operation{Operator: parser.Times, Left: parser.Operation{Operator: parser.Plus, Left: pish, Right: glob}, Right: prok}, 55, false},
+		{parser.Operation{Operator: parser.Divided, Left: pish, Right: prok}, 2, false},
+		{parser.Operation{Operator: parser.Divided, Left: pish, Right: parser.Operand{Units: []string{"glob", "glob", "glob"}}}, 0, true},
+		{parser.Operation{Operator: parser.Plus, Left: pish, Right: parser.Operand{Units: []string{"wood"}}}, 0, true},
+	}
+
+	for _, test := range tests {
+		result, err := calc.EvaluateExpression(test.input)
+		if test.hasError && err == nil {
+			t.Errorf("Expected error for input %v, got none", test.input)
+		}
+		if !test.hasError && err != nil {
+			t.Errorf("Unexpected error for input %v: %v", test.input, err)
+		}
+		if result != test.expected {
+			t.Errorf("EvaluateExpression(%v) = %d, want %d", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestOperate(t *testing.T) {
+	tests := []struct {
+		operator    string
+		left, right int
+		expected    int
+		message     string
+	}{
+		{parser.Plus, 40, 2, 42, ""},
+		{parser.Minus, 2, 40, -38, ""},
+		{parser.Times, 6, 7, 42, ""},
+		{parser.Divided, 84, 2, 42, ""},
+		{parser.Divided, 10, 3, 0, "10 divided by 3 can not be computed, the result is not a whole number"},
+		{parser.Divided, 10, 0, 0, "10 divided by 0 can not be computed, a number can not be divided by zero"},
+		{parser.Times, math.MaxInt, 2, 0, fmt.Sprintf("%d times 2 can not be computed, the result is too large", math.MaxInt)},
+	}
+
+	for _, test := range tests {
+		result, err := Operate(test.operator, test.left, test.right)
+		if test.message == "" {
+			if err != nil || result != test.expected {
+				t.Errorf("Operate(%s, %d, %d) = %d, %v, want %d", test.operator, test.left, test.right, result, err, test.expected)
+			}
+			continue
+		}
+
+		var arithmeticErr *constant.ArithmeticError
+		if !errors.As(err, &arithmeticErr) {
+			t.Errorf("Operate(%s, %d, %d) error = %v, want an *ArithmeticError", test.operator, test.left, test.right, err)
+			continue
+		}
+		if err.Error() != test.message {
+			t.Errorf("Operate(%s, %d, %d) error = %v, want %s", test.operator, test.left, test.right, err, test.message)
+		}
+	}
+}
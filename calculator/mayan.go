@@ -0,0 +1,97 @@
+package calculator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+type mayan struct{}
+
+// Mayan numerals are positional in base 20, every symbol is a digit from 0 to
+// 19 written in decimal, the most significant digit first
+var Mayan NumeralSystem = mayan{}
+
+const (
+	mayanBase = 20
+	// Keeps every value well inside an int
+	maxMayanDigits = 10
+)
+
+func (mayan) Name() string {
+	return "mayan"
+}
+
+func (mayan) IsSymbol(symbol string) bool {
+	_, ok := mayanDigit(symbol)
+	return ok
+}
+
+func (m mayan) Validate(symbols []string) error {
+	_, err := m.Parse(symbols)
+	return err
+}
+
+func (mayan) Parse(symbols []string) (int, error) {
+	numeral := strings.Join(symbols, " ")
+	total := 0
+
+	for i, symbol := range symbols {
+		digit, ok := mayanDigit(symbol)
+		rule := ""
+		switch {
+		case !ok:
+			rule = fmt.Sprintf("%s is not a mayan digit", symbol)
+		case i == 0 && digit == 0 && len(symbols) > 1:
+			// A leading zero would give two ways of writing the same number
+			rule = "a leading zero is never written"
+		case i >= maxMayanDigits:
+			rule = fmt.Sprintf("more than %d digits are written", maxMayanDigits)
+		}
+		if rule != "" {
+			return 0, &constant.InvalidNumeralError{Numeral: numeral, Position: i + 1, Rule: rule}
+		}
+		total = total*mayanBase + digit
+	}
+
+	return total, nil
+}
+
+// Terms writes every digit times the power of 20 of its position
+func (mayan) Terms(symbols []string) []Term {
+	terms := make([]Term, len(symbols))
+	power := 1
+	for i := len(symbols) - 1; i >= 0; i-- {
+		digit, _ := mayanDigit(symbols[i])
+		terms[i] = Term{Text: fmt.Sprintf("%s = %d × %d = %d", symbols[i], digit, power, digit*power), Value: digit * power}
+		power *= mayanBase
+	}
+	return terms
+}
+
+func (mayan) Format(number int) ([]string, error) {
+	if number < 0 {
+		return nil, constant.ErrInvalidFormat
+	}
+
+	symbols := []string{strconv.Itoa(number % mayanBase)}
+	for number /= mayanBase; number > 0; number /= mayanBase {
+		symbols = append([]string{strconv.Itoa(number % mayanBase)}, symbols...)
+	}
+	if len(symbols) > maxMayanDigits {
+		return nil, constant.ErrInvalidFormat
+	}
+
+	return symbols, nil
+}
+
+func mayanDigit(symbol string) (int, bool) {
+	digit, err := strconv.Atoi(symbol)
+	// Only the plain decimal spelling, "07" or "+7" are not digits
+	if err != nil || digit < 0 || digit >= mayanBase || strconv.Itoa(digit) != symbol {
+		return 0, false
+	}
+	return digit, true
+}
@@ -0,0 +1,63 @@
+package calculator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+func TestMayanParse(t *testing.T) {
+	tests := []struct {
+		input    []string
+		expected int
+		position int
+	}{
+		{[]string{"0"}, 0, 0},
+		{[]string{"19"}, 19, 0},
+		{[]string{"1", "0"}, 20, 0},
+		{[]string{"4", "17", "4"}, 1944, 0},
+		{[]string{"0", "1"}, 0, 1},
+		{[]string{"1", "20"}, 0, 2},
+		{[]string{"1", "07"}, 0, 2},
+		{[]string{"1", "0", "0", "0", "0", "0", "0", "0", "0", "0", "0"}, 0, 11},
+	}
+
+	for _, test := range tests {
+		result, err := Mayan.Parse(test.input)
+		if test.position == 0 {
+			if err != nil || result != test.expected {
+				t.Errorf("For input %v, expected %d, got %d (%v)", test.input, test.expected, result, err)
+			}
+			continue
+		}
+
+		var numeralErr *constant.InvalidNumeralError
+		if !errors.As(err, &numeralErr) || numeralErr.Position != test.position {
+			t.Errorf("For input %v, expected error at %d, got %v", test.input, test.position, err)
+		}
+	}
+}
+
+func TestMayanFormat(t *testing.T) {
+	tests := []struct {
+		input    int
+		expected []string
+	}{
+		{0, []string{"0"}},
+		{20, []string{"1", "0"}},
+		{1944, []string{"4", "17", "4"}},
+	}
+
+	for _, test := range tests {
+		result, err := Mayan.Format(test.input)
+		if err != nil || !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("For input %d, expected %v, got %v (%v)", test.input, test.expected, result, err)
+		}
+	}
+
+	if _, err := Mayan.Format(-1); !errors.Is(err, constant.ErrInvalidFormat) {
+		t.Errorf("Expected ErrInvalidFormat for -1, got %v", err)
+	}
+}
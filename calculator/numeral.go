@@ -0,0 +1,139 @@
+package calculator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+// NumeralSystem reads the symbols assigned to units as a number, and writes a
+// number back as symbols. Every symbol belongs to a single system, so the
+// symbols of a set of units select the system used to read them.
+type NumeralSystem interface {
+	Name() string
+	// IsSymbol reports whether a single symbol belongs to the system
+	IsSymbol(symbol string) bool
+	// Validate returns a *constant.InvalidNumeralError for the first symbol
+	// breaking a rule of the system
+	Validate(symbols []string) error
+	// Parse validates the symbols and returns their value
+	Parse(symbols []string) (int, error)
+	// Format returns the canonical symbols of a number
+	Format(number int) ([]string, error)
+	// Terms splits valid symbols into the values they add up to
+	Terms(symbols []string) []Term
+}
+
+// Term is a part of a numeral and the value it adds
+type Term struct {
+	// Text shows how the value is computed, e.g. IV = 5 - 1 = 4
+	Text  string
+	Value int
+}
+
+// NumeralSystems are the supported systems, Roman is the default one
+var NumeralSystems = []NumeralSystem{Roman, Attic, Mayan}
+
+// NumeralSystemByName finds a system ignoring case, an empty name is Roman
+func NumeralSystemByName(name string) (NumeralSystem, error) {
+	if name == "" {
+		return Roman, nil
+	}
+
+	for _, system := range NumeralSystems {
+		if strings.EqualFold(system.Name(), name) {
+			return system, nil
+		}
+	}
+
+	return nil, &constant.UnknownNumeralSystemError{System: name}
+}
+
+// IsNumeralSymbol reports whether any supported system has the symbol
+func IsNumeralSymbol(symbol string) bool {
+	for _, system := range NumeralSystems {
+		if system.IsSymbol(symbol) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanonicalSymbol returns the spelling a symbol is stored with, so that the
+// ASCII _V and the overlined V̅ are the same Roman symbol
+func CanonicalSymbol(symbol string) string {
+	return canonicalRomanSymbol(symbol)
+}
+
+// numeralSystemOf returns the system of the first symbol, checking that every
+// other symbol belongs to it too. No symbols at all are read with the first
+// system.
+func numeralSystemOf(systems []NumeralSystem, symbols []string) (NumeralSystem, error) {
+	if len(symbols) == 0 {
+		return systems[0], nil
+	}
+
+	var found NumeralSystem
+	for _, system := range systems {
+		if system.IsSymbol(symbols[0]) {
+			found = system
+			break
+		}
+	}
+	if found == nil {
+		return nil, &constant.InvalidNumeralError{
+			Numeral:  strings.Join(symbols, ""),
+			Position: 1,
+			Rule:     fmt.Sprintf("%s is not a numeral symbol", symbols[0]),
+		}
+	}
+
+	for i, symbol := range symbols {
+		if !found.IsSymbol(symbol) {
+			return nil, &constant.InvalidNumeralError{
+				Numeral:  strings.Join(symbols, ""),
+				Position: i + 1,
+				Rule:     fmt.Sprintf("%s is not a %s symbol", symbol, found.Name()),
+			}
+		}
+	}
+
+	return found, nil
+}
+
+func repeatValue(value, count int) []int {
+	values := make([]int, count)
+	for i := range values {
+		values[i] = value
+	}
+	return values
+}
+
+// sumTerm writes symbols worth the sum of values, XX = 10 + 10 = 20
+func sumTerm(symbols string, values []int) Term {
+	total := 0
+	parts := make([]string, len(values))
+	for i, value := range values {
+		total += value
+		parts[i] = strconv.Itoa(value)
+	}
+	if len(values) == 1 {
+		return Term{Text: fmt.Sprintf("%s = %d", symbols, total), Value: total}
+	}
+	return Term{Text: fmt.Sprintf("%s = %s = %d", symbols, strings.Join(parts, " + "), total), Value: total}
+}
+
+// differenceTerm writes symbols worth a larger value minus the subtracted
+// ones, IV = 5 - 1 = 4
+func differenceTerm(symbols string, larger int, subtracted []int) Term {
+	total := larger
+	parts := []string{strconv.Itoa(larger)}
+	for _, value := range subtracted {
+		total -= value
+		parts = append(parts, strconv.Itoa(value))
+	}
+	return Term{Text: fmt.Sprintf("%s = %s = %d", symbols, strings.Join(parts, " - "), total), Value: total}
+}
@@ -0,0 +1,99 @@
+package calculator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+func TestNumeralSystemByName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected NumeralSystem
+	}{
+		{"", Roman},
+		{"roman", Roman},
+		{"ATTIC", Attic},
+		{"mayan", Mayan},
+		{"babylonian", nil},
+	}
+
+	for _, test := range tests {
+		system, err := NumeralSystemByName(test.name)
+		if system != test.expected {
+			t.Errorf("NumeralSystemByName(%q) = %v, want %v", test.name, system, test.expected)
+		}
+		if test.expected == nil && !errors.Is(err, constant.ErrNotDefined) {
+			t.Errorf("NumeralSystemByName(%q) error = %v, want ErrNotDefined", test.name, err)
+		}
+	}
+}
+
+func TestNumeralSystemOf(t *testing.T) {
+	tests := []struct {
+		symbols  []string
+		expected NumeralSystem
+		position int
+	}{
+		{nil, Roman, 0},
+		{[]string{"X", "I"}, Roman, 0},
+		{[]string{"Δ", "Ι"}, Attic, 0},
+		{[]string{"1", "19"}, Mayan, 0},
+		{[]string{"X", "Δ"}, nil, 2},
+		{[]string{"Z"}, nil, 1},
+	}
+
+	for _, test := range tests {
+		system, err := numeralSystemOf(NumeralSystems, test.symbols)
+		if system != test.expected {
+			t.Errorf("numeralSystemOf(%v) = %v, want %v", test.symbols, system, test.expected)
+		}
+
+		var numeralErr *constant.InvalidNumeralError
+		if test.position != 0 && (!errors.As(err, &numeralErr) || numeralErr.Position != test.position) {
+			t.Errorf("numeralSystemOf(%v) error = %v, want position %d", test.symbols, err, test.position)
+		}
+	}
+}
+
+func TestIsNumeralSymbol(t *testing.T) {
+	for _, symbol := range []string{"I", "M", "Ι", "𐅇", "0", "19"} {
+		if !IsNumeralSymbol(symbol) {
+			t.Errorf("IsNumeralSymbol(%q) = false, want true", symbol)
+		}
+	}
+	for _, symbol := range []string{"", "Z", "IV", "20", "-1", "07"} {
+		if IsNumeralSymbol(symbol) {
+			t.Errorf("IsNumeralSymbol(%q) = true, want false", symbol)
+		}
+	}
+}
+
+func TestNumeralTerms(t *testing.T) {
+	tests := []struct {
+		system   NumeralSystem
+		input    []string
+		expected []Term
+	}{
+		{Roman, []string{"X", "L", "I", "I"}, []Term{{"XL = 50 - 10 = 40", 40}, {"II = 1 + 1 = 2", 2}}},
+		{Roman, []string{"M", "C", "M", "X", "C", "I", "V"}, []Term{
+			{"M = 1000", 1000}, {"CM = 1000 - 100 = 900", 900}, {"XC = 100 - 10 = 90", 90}, {"IV = 5 - 1 = 4", 4},
+		}},
+		{Roman, []string{"I̅", "V̅", "C", "C", "L"}, []Term{
+			{"I̅V̅ = 5000 - 1000 = 4000", 4000}, {"CC = 100 + 100 = 200", 200}, {"L = 50", 50},
+		}},
+		{LenientRoman, []string{"I", "I", "X"}, []Term{{"IIX = 10 - 1 - 1 = 8", 8}}},
+		{Attic, []string{"Δ", "Δ", "Π", "Ι"}, []Term{{"ΔΔ = 10 + 10 = 20", 20}, {"Π = 5", 5}, {"Ι = 1", 1}}},
+		{Mayan, []string{"4", "17", "4"}, []Term{
+			{"4 = 4 × 400 = 1600", 1600}, {"17 = 17 × 20 = 340", 340}, {"4 = 4 × 1 = 4", 4},
+		}},
+	}
+
+	for _, test := range tests {
+		if terms := test.system.Terms(test.input); !reflect.DeepEqual(terms, test.expected) {
+			t.Errorf("%s Terms(%v) = %v, want %v", test.system.Name(), test.input, terms, test.expected)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package calculator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+type roman struct {
+	lenient bool
+}
+
+var (
+	// Roman numerals with subtractive pairs. Up to MMMCMXCIX they are written
+	// with the usual symbols, larger numbers write their thousands with
+	// overlined (vinculum) symbols followed by the rest, e.g. I̅V̅CCL for 4250,
+	// up to M̅M̅M̅C̅M̅X̅C̅I̅X̅CMXCIX. Only the canonical form is accepted.
+	Roman NumeralSystem = roman{}
+	// LenientRoman reads the same symbols, also accepting historical forms:
+	// I, X, C and M repeated 4 times as in IIII for 4, a symbol subtracted
+	// twice as in IIX for 8, and overlined thousands below 4000. The other
+	// rules still apply, VV and IL are not numerals.
+	LenientRoman NumeralSystem = roman{lenient: true}
+)
+
+var (
+	RomanValues = map[byte]int{
+		'I': 1,
+		'V': 5,
+		'X': 10,
+		'L': 50,
+		'C': 100,
+		'D': 500,
+		'M': 1000,
+	}
+
+	validSubtractions = map[byte][]byte{
+		'I': {'V', 'X'},
+		'X': {'L', 'C'},
+		'C': {'D', 'M'},
+	}
+
+	// Ordered from the largest value so the encoder can be greedy
+	romanEncodings = []struct {
+		value  int
+		symbol string
+	}{
+		{1000, "M"},
+		{900, "CM"},
+		{500, "D"},
+		{400, "CD"},
+		{100, "C"},
+		{90, "XC"},
+		{50, "L"},
+		{40, "XL"},
+		{10, "X"},
+		{9, "IX"},
+		{5, "V"},
+		{4, "IV"},
+		{1, "I"},
+	}
+)
+
+const (
+	maxRomanValue = 3999
+	// Overlined symbols are worth a thousand times their plain value
+	vinculumFactor   = 1000
+	maxVinculumValue = maxRomanValue*vinculumFactor + maxRomanValue%vinculumFactor
+
+	// combiningOverline is written right after a symbol, V̅
+	combiningOverline = "̅"
+	// asciiOverline is written right before a symbol, _V is the same as V̅
+	asciiOverline = "_"
+)
+
+func (roman) Name() string {
+	return "roman"
+}
+
+func (roman) IsSymbol(symbol string) bool {
+	_, _, ok := parseRomanSymbol(symbol)
+	return ok
+}
+
+func (r roman) Validate(symbols []string) error {
+	_, err := r.Parse(symbols)
+	return err
+}
+
+// Parse reads the overlined thousands first, then the plain rest. The value is
+// always read leniently, then checked against the rules, and a strict numeral
+// must also be the canonical way of writing that value.
+func (r roman) Parse(symbols []string) (int, error) {
+	numeral := strings.Join(symbols, "")
+
+	split := 0
+	for split < len(symbols) {
+		_, overlined, ok := parseRomanSymbol(symbols[split])
+		if !ok || !overlined {
+			break
+		}
+		split++
+	}
+
+	thousands, _, position, rule := parseRomanPart(symbols[:split], true)
+	if position != 0 {
+		return 0, &constant.InvalidNumeralError{Numeral: numeral, Position: position, Rule: rule}
+	}
+	rest, _, position, rule := parseRomanPart(symbols[split:], false)
+	if position != 0 {
+		return 0, &constant.InvalidNumeralError{Numeral: numeral, Position: split + position, Rule: rule}
+	}
+
+	value := thousands*vinculumFactor + rest
+	canonical := ""
+	if formatted, err := r.Format(value); err == nil {
+		canonical = strings.Join(formatted, "")
+	}
+	position, rule = romanRule(symbols, split, thousands, rest, r.lenient)
+	// The rules should catch every other spelling, this is only a safety net
+	if position == 0 && !r.lenient && strings.Join(romanCanonicalSymbols(symbols), "") != canonical {
+		position, rule = 1, "the numeral is not written in its canonical form"
+	}
+	if position != 0 {
+		return 0, &constant.InvalidNumeralError{Numeral: numeral, Position: position, Rule: rule, Canonical: canonical}
+	}
+
+	return value, nil
+}
+
+// Terms splits the overlined thousands and the plain rest in runs of a
+// symbol, added or subtracted from the next larger one
+func (roman) Terms(symbols []string) []Term {
+	split := 0
+	for split < len(symbols) {
+		if _, overlined, ok := parseRomanSymbol(symbols[split]); !ok || !overlined {
+			break
+		}
+		split++
+	}
+
+	_, thousands, _, _ := parseRomanPart(symbols[:split], true)
+	_, rest, _, _ := parseRomanPart(symbols[split:], false)
+	return append(thousands, rest...)
+}
+
+func (roman) Format(number int) ([]string, error) {
+	if number < 1 || number > maxVinculumValue {
+		return nil, constant.ErrInvalidFormat
+	}
+	if number <= maxRomanValue {
+		return formatRomanPart(number, ""), nil
+	}
+
+	symbols := formatRomanPart(number/vinculumFactor, combiningOverline)
+	return append(symbols, formatRomanPart(number%vinculumFactor, "")...), nil
+}
+
+// parseRomanPart leniently reads symbols that are all overlined or all plain.
+// A run of a symbol before a larger one is subtracted, so IIX is 8 and IIII
+// is 4. It returns the terms the value is the sum of, and the position of the
+// first unreadable symbol, counting from 1, with the rule it breaks.
+func parseRomanPart(symbols []string, overlined bool) (int, []Term, int, string) {
+	letters := make([]byte, len(symbols))
+	for i, symbol := range symbols {
+		letter, currOverlined, ok := parseRomanSymbol(symbol)
+		if !ok {
+			return 0, nil, i + 1, fmt.Sprintf("%s is not a roman symbol", symbol)
+		}
+		if currOverlined != overlined {
+			return 0, nil, i + 1, "overlined symbols are written before the others"
+		}
+		letters[i] = letter
+	}
+
+	factor := 1
+	if overlined {
+		factor = vinculumFactor
+	}
+
+	total := 0
+	terms := make([]Term, 0)
+	for i := 0; i < len(letters); {
+		end := i
+		for end < len(letters) && letters[end] == letters[i] {
+			end++
+		}
+		runValue := (end - i) * RomanValues[letters[i]]
+		run := repeatValue(RomanValues[letters[i]]*factor, end-i)
+
+		if end == len(letters) || RomanValues[letters[end]] < RomanValues[letters[i]] {
+			total += runValue
+			terms = append(terms, sumTerm(strings.Join(symbols[i:end], ""), run))
+			i = end
+			continue
+		}
+
+		larger := RomanValues[letters[end]]
+		if isFiveSymbol(letters[i]) {
+			return 0, nil, i + 1, fmt.Sprintf("%c is never subtracted", letters[i])
+		}
+		if runValue >= larger {
+			return 0, nil, i + 1, fmt.Sprintf("the subtracted symbols are worth more than %c", letters[end])
+		}
+		total += larger - runValue
+		terms = append(terms, differenceTerm(strings.Join(symbols[i:end+1], ""), larger*factor, run))
+		i = end + 1
+	}
+
+	return total, terms, 0, ""
+}
+
+// romanRule returns the position of the first symbol breaking a rule of the
+// standard form, or of the historical forms when lenient, with the rule, or 0
+// when the numeral follows them
+func romanRule(symbols []string, split, thousands, rest int, lenient bool) (int, string) {
+	if split > 0 && thousands < 4 && !lenient {
+		return 1, "overlined symbols are only used from 4000"
+	}
+	if split > 0 && rest >= vinculumFactor && !lenient {
+		return split + 1, "M is not used after overlined symbols"
+	}
+
+	letters := make([]byte, len(symbols))
+	for i, symbol := range symbols {
+		letters[i], _, _ = parseRomanSymbol(symbol)
+	}
+	if position, rule := romanPartRule(letters[:split], lenient); position != 0 {
+		return position, rule
+	}
+	if position, rule := romanPartRule(letters[split:], lenient); position != 0 {
+		return split + position, rule
+	}
+	return 0, ""
+}
+
+// romanPartRule checks plain letters against the repeat, subtraction and
+// ordering rules. A symbol, or a subtractive pair, is never worth more than
+// what the symbol before it allows: I after I, but nothing after IX. Lenient
+// letters may repeat a symbol 4 times and subtract it twice, IIX is then a
+// piece worth 8 allowed where the first I is.
+func romanPartRule(letters []byte, lenient bool) (int, string) {
+	maxRun, maxSubtracted := 3, 1
+	if lenient {
+		maxRun, maxSubtracted = 4, 2
+	}
+	allowance := -1
+	// runAllowance is the allowance before the current run of a symbol
+	runAllowance := -1
+	run := 0
+
+	for i := 0; i < len(letters); {
+		letter := letters[i]
+		if i > 0 && letters[i-1] == letter {
+			run++
+		} else {
+			run = 1
+			runAllowance = allowance
+		}
+
+		if run > 1 && isFiveSymbol(letter) {
+			return i + 1, fmt.Sprintf("%c is never repeated", letter)
+		}
+		if run > maxRun {
+			return i + 1, fmt.Sprintf("%c is repeated more than %d times", letter, maxRun)
+		}
+
+		value := RomanValues[letter]
+		pieceValue, pieceLength := value, 1
+		if i+1 < len(letters) && RomanValues[letters[i+1]] > value {
+			larger := letters[i+1]
+			switch {
+			case isFiveSymbol(letter):
+				return i + 1, fmt.Sprintf("%c is never subtracted", letter)
+			case !isValidSubtraction(letter, larger):
+				allowed := validSubtractions[letter]
+				return i + 1, fmt.Sprintf("%c is only subtracted from %c and %c", letter, allowed[0], allowed[1])
+			case run > maxSubtracted && !lenient:
+				return i + 1, fmt.Sprintf("a subtracted %c is never repeated", letter)
+			case run > maxSubtracted:
+				return i + 1, fmt.Sprintf("a subtracted %c is written at most twice", letter)
+			}
+			pieceValue, pieceLength = RomanValues[larger]-run*value, 2
+		}
+
+		// A symbol subtracted twice is a piece with the one before it
+		limit := allowance
+		if pieceLength == 2 && run > 1 {
+			limit = runAllowance
+		}
+		if limit >= 0 && pieceValue > limit {
+			return i + 1, "symbols are written from the largest value down"
+		}
+
+		switch {
+		case pieceLength == 2:
+			allowance = value - 1
+			run = 1
+		case isFiveSymbol(letter):
+			allowance = value / 5
+		default:
+			allowance = value
+		}
+		i += pieceLength
+	}
+
+	return 0, ""
+}
+
+func formatRomanPart(number int, mark string) []string {
+	symbols := make([]string, 0)
+	for _, encoding := range romanEncodings {
+		for number >= encoding.value {
+			for _, letter := range encoding.symbol {
+				symbols = append(symbols, string(letter)+mark)
+			}
+			number -= encoding.value
+		}
+	}
+
+	return symbols
+}
+
+// parseRomanSymbol returns the letter of a symbol and whether it is overlined,
+// in either the combining or the ASCII notation
+func parseRomanSymbol(symbol string) (byte, bool, bool) {
+	overlined := false
+	switch {
+	case strings.HasSuffix(symbol, combiningOverline):
+		symbol = strings.TrimSuffix(symbol, combiningOverline)
+		overlined = true
+	case strings.HasPrefix(symbol, asciiOverline):
+		symbol = strings.TrimPrefix(symbol, asciiOverline)
+		overlined = true
+	}
+
+	if len(symbol) != 1 {
+		return 0, false, false
+	}
+	if _, exists := RomanValues[symbol[0]]; !exists {
+		return 0, false, false
+	}
+	return symbol[0], overlined, true
+}
+
+// canonicalRomanSymbol writes an overlined symbol with the combining overline
+func canonicalRomanSymbol(symbol string) string {
+	letter, overlined, ok := parseRomanSymbol(symbol)
+	if !ok || !overlined {
+		return symbol
+	}
+	return string(letter) + combiningOverline
+}
+
+func romanCanonicalSymbols(symbols []string) []string {
+	canonical := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		canonical[i] = canonicalRomanSymbol(symbol)
+	}
+	return canonical
+}
+
+// V, L and D are the only symbols that are neither repeated nor subtracted
+func isFiveSymbol(letter byte) bool {
+	return letter == 'V' || letter == 'L' || letter == 'D'
+}
+
+func isValidSubtraction(smaller, larger byte) bool {
+	allowed, exists := validSubtractions[smaller]
+	if !exists {
+		return false
+	}
+
+	for _, symbol := range allowed {
+		if symbol == larger {
+			return true
+		}
+	}
+
+	return false
+}
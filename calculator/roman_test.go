@@ -0,0 +1,222 @@
+package calculator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+func TestRomanParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		position int
+	}{
+		{"IIII", 4},
+		{"XIIII", 5},
+		{"VV", 2},
+		{"IL", 1},
+		{"MZ", 2},
+		{"IV̅", 2},
+		{"V̅V̅", 2},
+		{"I̅V̅MC", 3},
+		{"_I_V_I_I_I_I", 3},
+	}
+
+	for _, test := range tests {
+		_, err := Roman.Parse(romanSymbols(test.input))
+
+		var numeralErr *constant.InvalidNumeralError
+		if !errors.As(err, &numeralErr) {
+			t.Errorf("Expected InvalidNumeralError for %s, got %v", test.input, err)
+			continue
+		}
+		if numeralErr.Numeral != test.input || numeralErr.Position != test.position {
+			t.Errorf("For input %s, expected position %d, got %+v", test.input, test.position, numeralErr)
+		}
+		if !errors.Is(err, constant.ErrInvalidFormat) {
+			t.Errorf("Expected %v to be ErrInvalidFormat", err)
+		}
+	}
+}
+
+// romanSymbols splits a numeral into symbols, keeping overlines with their letter
+func romanSymbols(numeral string) []string {
+	symbols := make([]string, 0)
+	prefix := ""
+	for _, letter := range numeral {
+		switch {
+		case string(letter) == asciiOverline:
+			prefix = asciiOverline
+		case string(letter) == combiningOverline:
+			symbols[len(symbols)-1] += combiningOverline
+		default:
+			symbols = append(symbols, prefix+string(letter))
+			prefix = ""
+		}
+	}
+	return symbols
+}
+
+func TestRomanParseVinculum(t *testing.T) {
+	tests := []struct {
+		input    []string
+		expected int
+	}{
+		{[]string{"V̅"}, 5000},
+		{[]string{"_V"}, 5000},
+		{[]string{"_I", "V̅", "C", "C", "L"}, 4250},
+		{[]string{"M̅", "C", "M"}, 1000900},
+	}
+
+	for _, test := range tests {
+		result, err := Roman.Parse(test.input)
+		if err != nil || result != test.expected {
+			t.Errorf("For input %v, expected %d, got %d (%v)", test.input, test.expected, result, err)
+		}
+	}
+}
+
+func TestRomanStrictRules(t *testing.T) {
+	tests := []struct {
+		input     string
+		position  int
+		rule      string
+		canonical string
+	}{
+		{"IIX", 2, "a subtracted I is never repeated", "VIII"},
+		{"XXC", 2, "a subtracted X is never repeated", "LXXX"},
+		{"IXI", 3, "symbols are written from the largest value down", "X"},
+		{"XCX", 3, "symbols are written from the largest value down", "C"},
+		{"VIV", 2, "symbols are written from the largest value down", "IX"},
+		{"CMD", 3, "symbols are written from the largest value down", "MCD"},
+		{"IC", 1, "I is only subtracted from V and X", "XCIX"},
+		{"VX", 1, "V is never subtracted", ""},
+		{"LL", 2, "L is never repeated", "C"},
+		{"MMMM", 4, "M is repeated more than 3 times", "I̅V̅"},
+		{"_I_I", 1, "overlined symbols are only used from 4000", "MM"},
+		{"_I_VMM", 3, "M is not used after overlined symbols", "V̅I̅"},
+		{"I_V", 2, "overlined symbols are written before the others", ""},
+		{"IIIIIV", 1, "the subtracted symbols are worth more than V", ""},
+	}
+
+	for _, test := range tests {
+		_, err := Roman.Parse(romanSymbols(test.input))
+
+		var numeralErr *constant.InvalidNumeralError
+		if !errors.As(err, &numeralErr) {
+			t.Errorf("Expected InvalidNumeralError for %s, got %v", test.input, err)
+			continue
+		}
+		if numeralErr.Position != test.position || numeralErr.Rule != test.rule || numeralErr.Canonical != test.canonical {
+			t.Errorf("For input %s, expected %d %q %q, got %+v", test.input, test.position, test.rule, test.canonical, numeralErr)
+		}
+	}
+}
+
+func TestLenientRomanParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"IIII", 4},
+		{"IIX", 8},
+		{"XIIX", 18},
+		{"XXXX", 40},
+		{"XXC", 80},
+		{"MCCCCXXXXIIII", 1444},
+		{"MMMM", 4000},
+		{"_I_I", 2000},
+		{"MCMXLIV", 1944},
+	}
+
+	for _, test := range tests {
+		result, err := LenientRoman.Parse(romanSymbols(test.input))
+		if err != nil || result != test.expected {
+			t.Errorf("For input %s, expected %d, got %d (%v)", test.input, test.expected, result, err)
+		}
+	}
+
+	// Only the repeat limit and the double subtraction are loosened
+	invalid := []struct {
+		input    string
+		position int
+		rule     string
+	}{
+		{"VX", 1, "V is never subtracted"},
+		{"VV", 2, "V is never repeated"},
+		{"LL", 2, "L is never repeated"},
+		{"DD", 2, "D is never repeated"},
+		{"IL", 1, "I is only subtracted from V and X"},
+		{"IC", 1, "I is only subtracted from V and X"},
+		{"XM", 1, "X is only subtracted from L and C"},
+		{"IIIII", 5, "I is repeated more than 4 times"},
+		{"IIIX", 3, "a subtracted I is written at most twice"},
+		{"IXI", 3, "symbols are written from the largest value down"},
+		{"IIXX", 4, "symbols are written from the largest value down"},
+	}
+
+	for _, test := range invalid {
+		_, err := LenientRoman.Parse(romanSymbols(test.input))
+		var numeralErr *constant.InvalidNumeralError
+		if !errors.As(err, &numeralErr) || numeralErr.Position != test.position || numeralErr.Rule != test.rule {
+			t.Errorf("For input %s, expected symbol %d to break %q, got %v", test.input, test.position, test.rule, err)
+		}
+	}
+}
+
+func TestCanonicalSymbol(t *testing.T) {
+	tests := map[string]string{
+		"_V": "V̅",
+		"V̅": "V̅",
+		"V":  "V",
+		"_Z": "_Z",
+		"Δ":  "Δ",
+	}
+
+	for input, expected := range tests {
+		if result := CanonicalSymbol(input); result != expected {
+			t.Errorf("CanonicalSymbol(%q) = %q, want %q", input, result, expected)
+		}
+	}
+}
+
+func TestRomanFormat(t *testing.T) {
+	tests := []struct {
+		input    int
+		expected string
+	}{
+		{1, "I"},
+		{4, "IV"},
+		{14, "XIV"},
+		{1944, "MCMXLIV"},
+		{3999, "MMMCMXCIX"},
+		{4000, "I̅V̅"},
+		{4250, "I̅V̅CCL"},
+		{1234567, "M̅C̅C̅X̅X̅X̅I̅V̅DLXVII"},
+		{3999999, "M̅M̅M̅C̅M̅X̅C̅I̅X̅CMXCIX"},
+	}
+
+	for _, test := range tests {
+		result, err := Roman.Format(test.input)
+		if err != nil {
+			t.Errorf("Unexpected error for input %d: %v", test.input, err)
+		}
+		if strings.Join(result, "") != test.expected {
+			t.Errorf("For input %d, expected %s, got %v", test.input, test.expected, result)
+		}
+
+		// Encoding then decoding must give back the same number
+		decoded, err := Roman.Parse(result)
+		if err != nil || decoded != test.input {
+			t.Errorf("Round trip of %d gave %d (%v)", test.input, decoded, err)
+		}
+	}
+
+	for _, input := range []int{0, 4000000} {
+		if _, err := Roman.Format(input); !errors.Is(err, constant.ErrInvalidFormat) {
+			t.Errorf("Expected ErrInvalidFormat for input %d, got %v", input, err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package calculator
+
+import (
+	"strings"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+	"github.com/erizkiatama/prospace-assignment/database"
+)
+
+// Workspace opens the database of a namespace, with a calculator resolving
+// units and currencies only inside it. An empty name is the namespace used by
+// default. A namespace that does not exist yet is only created with create,
+// it is unknown otherwise.
+type Workspace func(namespace string, create bool) (database.Database, Calculator, error)
+
+// SingleWorkspace serves db as the only namespace, the default one
+func SingleWorkspace(db database.Database, calc Calculator) Workspace {
+	return func(namespace string, create bool) (database.Database, Calculator, error) {
+		if namespace != "" && !strings.EqualFold(namespace, database.DefaultNamespace) {
+			return nil, nil, &constant.UnknownNamespaceError{Namespace: strings.ToLower(namespace)}
+		}
+		return db, calc, nil
+	}
+}
+
+// NewWorkspace serves every namespace of namespaces, fallback is used when no
+// namespace is named
+func NewWorkspace(namespaces *database.Namespaces, fallback string, options ...Option) Workspace {
+	return func(namespace string, create bool) (database.Database, Calculator, error) {
+		if namespace == "" {
+			namespace = fallback
+		}
+
+		get := namespaces.Find
+		if create {
+			get = namespaces.Get
+		}
+		db, err := get(namespace)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, NewCalculator(db, options...), nil
+	}
+}
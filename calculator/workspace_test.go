@@ -0,0 +1,62 @@
+package calculator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+	"github.com/erizkiatama/prospace-assignment/database"
+)
+
+func TestSingleWorkspace(t *testing.T) {
+	db := newMockDatabase()
+	calc := NewCalculator(db)
+	workspace := SingleWorkspace(db, calc)
+
+	for _, namespace := range []string{"", "default", "Default"} {
+		if gotDB, gotCalc, err := workspace(namespace, false); err != nil || gotDB != db || gotCalc != calc {
+			t.Errorf("Expected the single database for %q, got %v", namespace, err)
+		}
+	}
+
+	var unknown *constant.UnknownNamespaceError
+	if _, _, err := workspace("team-a", true); !errors.As(err, &unknown) || unknown.Namespace != "team-a" {
+		t.Errorf("Expected UnknownNamespaceError, got %v", err)
+	}
+}
+
+func TestNewWorkspace(t *testing.T) {
+	namespaces := database.NewNamespaces(func(name string) (database.Database, error) {
+		return database.NewDatabase(), nil
+	}, nil)
+	workspace := NewWorkspace(namespaces, "team-a")
+
+	teamA, calcA, err := workspace("", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	teamA.DefineUnit("glob", "I", calcA.RateFromSymbols)
+	teamB, calcB, err := workspace("team-b", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	teamB.DefineUnit("glob", "X", calcB.RateFromSymbols)
+
+	// The calculator of a namespace only sees its units
+	_, calcA, _ = workspace("team-a", false)
+	if value, err := calcA.ConvertUnitsToInt([]string{"glob", "glob"}); err != nil || value != 2 {
+		t.Errorf("Expected 2 in team-a, got %d (%v)", value, err)
+	}
+	if value, err := calcB.ConvertUnitsToInt([]string{"glob", "glob"}); err != nil || value != 20 {
+		t.Errorf("Expected 20 in team-b, got %d (%v)", value, err)
+	}
+	if _, _, err := workspace("team b", true); err == nil {
+		t.Error("Expected an error for an invalid namespace, got none")
+	}
+
+	// Only a workspace asked to create a namespace opens it
+	var unknown *constant.UnknownNamespaceError
+	if _, _, err := workspace("team-c", false); !errors.As(err, &unknown) {
+		t.Errorf("Expected UnknownNamespaceError, got %v", err)
+	}
+}
@@ -1,9 +1,325 @@
 package constant
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Code is a stable, machine readable kind of error, safe to branch on in
+// clients and tests instead of comparing messages
+type Code string
+
+const (
+	CodeInvalidNumber     Code = "invalid_number"
+	CodeInvalidNumeral    Code = "invalid_numeral"
+	CodeInvalidCredit     Code = "invalid_credit"
+	CodeParseFailure      Code = "parse_failure"
+	CodeUnknownUnit       Code = "unknown_unit"
+	CodeUnknownCurrency   Code = "unknown_currency"
+	CodeUnknownName       Code = "unknown_name"
+	CodeNoRate            Code = "no_rate"
+	CodeUnassignedSymbol  Code = "unassigned_symbol"
+	CodeUnknownSystem     Code = "unknown_numeral_system"
+	CodeConflict          Code = "conflict"
+	CodeTransaction       Code = "invalid_transaction"
+	CodeUnknownNamespace  Code = "unknown_namespace"
+	CodeInvalidNamespace  Code = "invalid_namespace"
+	CodeInvalidDocument   Code = "invalid_document"
+	CodeInvalidArithmetic Code = "invalid_arithmetic"
+	CodeZeroRate          Code = "zero_rate"
+	CodeInternal          Code = "internal"
+)
+
+// Error is implemented by every error of the catalogue
+type Error interface {
+	error
+	Code() Code
+}
 
 var (
-	ErrInvalidFormat = errors.New("requested number is in invalid format")
-	ErrInvalidParse  = errors.New("i have no idea what are you talking about")
-	ErrInvalidCredit = errors.New("credits is not a number")
+	ErrInvalidFormat = newError(CodeInvalidNumber, "requested number is in invalid format")
+	ErrInvalidParse  = newError(CodeParseFailure, "i have no idea what are you talking about")
+	ErrInvalidCredit = newError(CodeInvalidCredit, "credits is not a number")
+	ErrNotDefined    = errors.New("is not defined in the intergalactic database")
+	ErrNotAssigned   = errors.New("has no unit assigned in the intergalactic database")
+	// ErrAlreadyDefined is matched by every *ConflictError
+	ErrAlreadyDefined = errors.New("is already defined in the intergalactic database")
+	ErrInTransaction  = newError(CodeTransaction, "a transaction is already in progress")
+	ErrNoTransaction  = newError(CodeTransaction, "no transaction is in progress")
+	ErrNothingToUndo  = newError(CodeTransaction, "there is nothing to undo")
+	ErrNothingToRedo  = newError(CodeTransaction, "there is nothing to redo")
+	// ErrInvalidDocument is a structured statement that can not be read
+	ErrInvalidDocument = newError(CodeInvalidDocument, "invalid document")
 )
+
+type codedError struct {
+	code    Code
+	message string
+}
+
+func newError(code Code, message string) error {
+	return &codedError{code: code, message: message}
+}
+
+func (e *codedError) Error() string {
+	return e.message
+}
+
+func (e *codedError) Code() Code {
+	return e.code
+}
+
+// UnknownUnitError is returned when a unit has no roman symbol assigned
+type UnknownUnitError struct {
+	Unit string
+}
+
+func (e *UnknownUnitError) Error() string {
+	return fmt.Sprintf("%s unit %s", e.Unit, ErrNotDefined)
+}
+
+func (e *UnknownUnitError) Code() Code {
+	return CodeUnknownUnit
+}
+
+func (e *UnknownUnitError) Is(target error) bool {
+	return target == ErrNotDefined
+}
+
+// UnknownCurrencyError is returned when a currency has no credits defined
+type UnknownCurrencyError struct {
+	Currency string
+}
+
+func (e *UnknownCurrencyError) Error() string {
+	return fmt.Sprintf("%s currency %s", e.Currency, ErrNotDefined)
+}
+
+func (e *UnknownCurrencyError) Code() Code {
+	return CodeUnknownCurrency
+}
+
+func (e *UnknownCurrencyError) Is(target error) bool {
+	return target == ErrNotDefined
+}
+
+// UnknownNameError is returned when a name is neither a unit nor a currency
+type UnknownNameError struct {
+	Name string
+}
+
+func (e *UnknownNameError) Error() string {
+	return fmt.Sprintf("%s is neither a unit nor a currency in the intergalactic database", e.Name)
+}
+
+func (e *UnknownNameError) Code() Code {
+	return CodeUnknownName
+}
+
+func (e *UnknownNameError) Is(target error) bool {
+	return target == ErrNotDefined
+}
+
+// UnassignedSymbolError is returned when a number needs a numeral symbol that
+// no unit is assigned to
+type UnassignedSymbolError struct {
+	Symbol string
+}
+
+func (e *UnassignedSymbolError) Error() string {
+	return fmt.Sprintf("%s numeral symbol %s", e.Symbol, ErrNotAssigned)
+}
+
+func (e *UnassignedSymbolError) Code() Code {
+	return CodeUnassignedSymbol
+}
+
+func (e *UnassignedSymbolError) Is(target error) bool {
+	return target == ErrNotAssigned
+}
+
+// InvalidNumeralError is returned when a numeral breaks a rule of its system, at the
+// symbol found at Position, counting from 1. Rule tells which rule is broken
+// and Canonical how the numeral should have been written, when known.
+type InvalidNumeralError struct {
+	Numeral   string
+	Position  int
+	Rule      string
+	Canonical string
+}
+
+func (e *InvalidNumeralError) Error() string {
+	message := fmt.Sprintf("%s: %s is invalid at symbol %d", ErrInvalidFormat, e.Numeral, e.Position)
+	if e.Rule != "" {
+		message += ", " + e.Rule
+	}
+	if e.Canonical != "" {
+		message += ", write it as " + e.Canonical
+	}
+	return message
+}
+
+func (e *InvalidNumeralError) Code() Code {
+	return CodeInvalidNumeral
+}
+
+func (e *InvalidNumeralError) Is(target error) bool {
+	return target == ErrInvalidFormat
+}
+
+// NoRateError is returned when a currency was not defined yet at a time
+type NoRateError struct {
+	Currency string
+	At       time.Time
+}
+
+func (e *NoRateError) Error() string {
+	return fmt.Sprintf("%s currency has no rate yet on %s", e.Currency, e.At.Format(time.DateOnly))
+}
+
+func (e *NoRateError) Code() Code {
+	return CodeNoRate
+}
+
+func (e *NoRateError) Is(target error) bool {
+	return target == ErrNotDefined
+}
+
+// UnknownNamespaceError is returned when a namespace is not available
+type UnknownNamespaceError struct {
+	Namespace string
+}
+
+func (e *UnknownNamespaceError) Error() string {
+	return fmt.Sprintf("%s namespace %s", e.Namespace, ErrNotDefined)
+}
+
+func (e *UnknownNamespaceError) Code() Code {
+	return CodeUnknownNamespace
+}
+
+func (e *UnknownNamespaceError) Is(target error) bool {
+	return target == ErrNotDefined
+}
+
+// InvalidNamespaceError is returned when a namespace name can not be used
+type InvalidNamespaceError struct {
+	Namespace string
+}
+
+func (e *InvalidNamespaceError) Error() string {
+	return fmt.Sprintf("%q is not a valid namespace, use up to 32 letters, digits, - and _", e.Namespace)
+}
+
+func (e *InvalidNamespaceError) Code() Code {
+	return CodeInvalidNamespace
+}
+
+// ZeroRateError is returned when a quantity of a currency worth no credits is
+// asked for, since any amount of credits would buy an infinite quantity
+type ZeroRateError struct {
+	Currency string
+}
+
+func (e *ZeroRateError) Error() string {
+	return fmt.Sprintf("%s currency is worth 0 Credits, a quantity of it can not be computed", e.Currency)
+}
+
+func (e *ZeroRateError) Code() Code {
+	return CodeZeroRate
+}
+
+// ArithmeticError is returned when an operation of an expression has no
+// whole result, Reason tells why
+type ArithmeticError struct {
+	Operation string
+	Reason    string
+}
+
+func (e *ArithmeticError) Error() string {
+	return fmt.Sprintf("%s can not be computed, %s", e.Operation, e.Reason)
+}
+
+func (e *ArithmeticError) Code() Code {
+	return CodeInvalidArithmetic
+}
+
+// UnknownNumeralSystemError is returned when a number is asked in a numeral
+// system that is not supported
+type UnknownNumeralSystemError struct {
+	System string
+}
+
+func (e *UnknownNumeralSystemError) Error() string {
+	return fmt.Sprintf("%s numeral system %s", e.System, ErrNotDefined)
+}
+
+func (e *UnknownNumeralSystemError) Code() Code {
+	return CodeUnknownSystem
+}
+
+func (e *UnknownNumeralSystemError) Is(target error) bool {
+	return target == ErrNotDefined
+}
+
+// ConflictError is returned when a definition would change an existing one.
+// Kind is unit, symbol or currency, Current what Name is defined as now.
+type ConflictError struct {
+	Kind    string
+	Name    string
+	Current string
+}
+
+func (e *ConflictError) Error() string {
+	if e.Kind == "currency" {
+		return fmt.Sprintf("%s currency is already worth %s", e.Name, e.Current)
+	}
+	return fmt.Sprintf("%s %s is already assigned to %s", e.Name, e.Kind, e.Current)
+}
+
+func (e *ConflictError) Code() Code {
+	return CodeConflict
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrAlreadyDefined
+}
+
+// Warning wraps an error that did not stop the change from being applied
+type Warning struct {
+	Err error
+}
+
+func (w *Warning) Error() string {
+	return "warning: " + w.Err.Error()
+}
+
+func (w *Warning) Unwrap() error {
+	return w.Err
+}
+
+// IsWarning reports whether err only warns about a change that was applied
+func IsWarning(err error) bool {
+	var warning *Warning
+	return errors.As(err, &warning)
+}
+
+// SplitWarning splits the warning of a change that was applied anyway from the
+// errors that stopped it, the warning is returned as its bare message
+func SplitWarning(err error) (string, error) {
+	var warning *Warning
+	if errors.As(err, &warning) {
+		return warning.Err.Error(), nil
+	}
+	return "", err
+}
+
+// CodeOf returns the code of the first error of the catalogue in the chain
+func CodeOf(err error) Code {
+	var coded Error
+	if errors.As(err, &coded) {
+		return coded.Code()
+	}
+	return CodeInternal
+}
@@ -0,0 +1,86 @@
+package constant
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected Code
+		is       error
+		message  string
+	}{
+		{ErrInvalidFormat, CodeInvalidNumber, ErrInvalidFormat, "requested number is in invalid format"},
+		{ErrInvalidParse, CodeParseFailure, ErrInvalidParse, "i have no idea what are you talking about"},
+		{ErrInvalidCredit, CodeInvalidCredit, ErrInvalidCredit, "credits is not a number"},
+		{ErrNoTransaction, CodeTransaction, ErrNoTransaction, "no transaction is in progress"},
+		{ErrNothingToUndo, CodeTransaction, ErrNothingToUndo, "there is nothing to undo"},
+		{ErrInvalidDocument, CodeInvalidDocument, ErrInvalidDocument, "invalid document"},
+		{&UnknownUnitError{Unit: "glob"}, CodeUnknownUnit, ErrNotDefined, "glob unit is not defined in the intergalactic database"},
+		{&UnknownCurrencyError{Currency: "gold"}, CodeUnknownCurrency, ErrNotDefined, "gold currency is not defined in the intergalactic database"},
+		{&UnknownNameError{Name: "silver"}, CodeUnknownName, ErrNotDefined, "silver is neither a unit nor a currency in the intergalactic database"},
+		{&UnassignedSymbolError{Symbol: "M"}, CodeUnassignedSymbol, ErrNotAssigned, "M numeral symbol has no unit assigned in the intergalactic database"},
+		{&UnknownNumeralSystemError{System: "babylonian"}, CodeUnknownSystem, ErrNotDefined, "babylonian numeral system is not defined in the intergalactic database"},
+		{&NoRateError{Currency: "gold", At: time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)}, CodeNoRate, ErrNotDefined, "gold currency has no rate yet on 2025-12-31"},
+		{&UnknownNamespaceError{Namespace: "team-a"}, CodeUnknownNamespace, ErrNotDefined, "team-a namespace is not defined in the intergalactic database"},
+		{&InvalidNamespaceError{Namespace: "../etc"}, CodeInvalidNamespace, nil, `"../etc" is not a valid namespace, use up to 32 letters, digits, - and _`},
+		{&ZeroRateError{Currency: "dust"}, CodeZeroRate, nil, "dust currency is worth 0 Credits, a quantity of it can not be computed"},
+		{&ArithmeticError{Operation: "10 divided by 3", Reason: "the result is not a whole number"}, CodeInvalidArithmetic, nil, "10 divided by 3 can not be computed, the result is not a whole number"},
+		{&InvalidNumeralError{Numeral: "IIII", Position: 1}, CodeInvalidNumeral, ErrInvalidFormat, "requested number is in invalid format: IIII is invalid at symbol 1"},
+		{&InvalidNumeralError{Numeral: "IIX", Position: 1, Rule: "a subtracted I is never repeated", Canonical: "VIII"}, CodeInvalidNumeral, ErrInvalidFormat, "requested number is in invalid format: IIX is invalid at symbol 1, a subtracted I is never repeated, write it as VIII"},
+		{fmt.Errorf("wrapped: %w", &UnknownUnitError{Unit: "glob"}), CodeUnknownUnit, ErrNotDefined, "wrapped: glob unit is not defined in the intergalactic database"},
+		{errors.New("disk is full"), CodeInternal, nil, "disk is full"},
+	}
+
+	for _, test := range tests {
+		if code := CodeOf(test.err); code != test.expected {
+			t.Errorf("CodeOf(%v) = %s, want %s", test.err, code, test.expected)
+		}
+		if test.is != nil && !errors.Is(test.err, test.is) {
+			t.Errorf("errors.Is(%v, %v) = false, want true", test.err, test.is)
+		}
+		if test.err.Error() != test.message {
+			t.Errorf("Error() = %s, want %s", test.err.Error(), test.message)
+		}
+	}
+}
+
+func TestErrorsAs(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &InvalidNumeralError{Numeral: "IIX", Position: 1})
+
+	var numeralErr *InvalidNumeralError
+	if !errors.As(err, &numeralErr) {
+		t.Fatalf("errors.As(%v) = false, want true", err)
+	}
+	if numeralErr.Numeral != "IIX" || numeralErr.Position != 1 {
+		t.Errorf("errors.As() = %+v, want IIX at 1", numeralErr)
+	}
+	if errors.Is(err, ErrNotDefined) {
+		t.Errorf("errors.Is(%v, ErrNotDefined) = true, want false", err)
+	}
+}
+
+func TestSplitWarning(t *testing.T) {
+	conflict := &ConflictError{Kind: "unit", Name: "glob", Current: "I"}
+
+	tests := []struct {
+		err      error
+		warning  string
+		expected error
+	}{
+		{nil, "", nil},
+		{&Warning{Err: conflict}, "glob unit is already assigned to I", nil},
+		{conflict, "", conflict},
+	}
+
+	for _, test := range tests {
+		warning, err := SplitWarning(test.err)
+		if warning != test.warning || err != test.expected {
+			t.Errorf("SplitWarning(%v) = %q, %v, want %q, %v", test.err, warning, err, test.warning, test.expected)
+		}
+	}
+}
@@ -1,49 +1,482 @@
 package database
 
 import (
-	"errors"
+	"fmt"
+	"math/big"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
 )
 
 type Database interface {
-	AddUnitToRomanMapping(string, string)
 	GetRomanFromUnit(string) (string, error)
-	AddCurrencyToCreditsMapping(string, float32)
-	GetCreditsFromCurrency(string) (float32, error)
+	GetUnitFromRoman(string) (string, error)
+	GetCreditsFromCurrency(string) (*big.Rat, error)
+	GetCreditsFromCurrencies(...string) ([]*big.Rat, error)
+	Read(units, currencies []string) Reading
+	RemoveCurrency(string) error
+	DefineUnit(string, string, RateFunc) ([]string, error)
+	ForgetUnit(string, RateFunc) ([]string, error)
+	DefineRate(string, RateSource, RateFunc) (*big.Rat, error)
+	GetRateSource(string) (RateSource, error)
+	GetCreditsFromCurrencyAt(string, time.Time) (*big.Rat, error)
+	GetRateHistory(string) ([]RatePoint, error)
+	ListUnits() []string
+	ListCurrencies() []string
+	Snapshot(Scope) Snapshot
+	Restore(Snapshot) error
+}
+
+// RateSource is the sentence a currency rate was computed from,
+// `{units} {currency} is {credits} Credits`. A stale rate could not be
+// computed again after one of its units changed, so it is the last one known.
+type RateSource struct {
+	Units   []string
+	Credits *big.Rat
+	Stale   bool
+}
+
+// RateFunc computes the rate of a sentence from the symbols its units are
+// assigned to and its credits. It is given no database, it runs while the
+// database is locked.
+type RateFunc func(symbols []string, credits *big.Rat) (*big.Rat, error)
+
+// Policy decides what happens to a definition that changes an existing one,
+// or assigns a symbol that is already assigned to another unit
+type Policy int
+
+const (
+	// PolicyWarn applies the definition and returns a *constant.Warning
+	PolicyWarn Policy = iota
+	// PolicyReject keeps the database as it is and returns a *constant.ConflictError
+	PolicyReject
+	// PolicyOverwrite applies the definition silently
+	PolicyOverwrite
+)
+
+var policyNames = map[string]Policy{
+	"warn":      PolicyWarn,
+	"reject":    PolicyReject,
+	"overwrite": PolicyOverwrite,
+}
+
+// ParsePolicy reads a policy from its name, warn, reject or overwrite
+func ParsePolicy(name string) (Policy, error) {
+	if policy, exists := policyNames[strings.ToLower(name)]; exists {
+		return policy, nil
+	}
+	return 0, fmt.Errorf("unknown redefinition policy %q, use warn, reject or overwrite", name)
+}
+
+// Option changes how a database handles definitions
+type Option func(*database)
+
+// WithPolicy sets the redefinition policy, PolicyWarn by default
+func WithPolicy(policy Policy) Option {
+	return func(db *database) {
+		db.policy = policy
+	}
 }
 
+// database is safe for concurrent use, every method holds mu for its whole
+// work so a change is never seen half done. The unexported methods expect mu
+// to be held already.
 type database struct {
+	mu sync.RWMutex
+
 	unitToRomanValues      map[string]string
-	currencyToCreditValues map[string]float32
+	currencyToCreditValues map[string]*big.Rat
+	currencyToSources      map[string]RateSource
+	currencyToHistory      map[string][]RatePoint
+	policy                 Policy
+	clock                  Clock
+}
+
+func NewDatabase(options ...Option) Database {
+	return newDatabase(options)
 }
 
-func NewDatabase() Database {
-	return &database{
+func newDatabase(options []Option) *database {
+	db := &database{
 		unitToRomanValues:      make(map[string]string),
-		currencyToCreditValues: make(map[string]float32),
+		currencyToCreditValues: make(map[string]*big.Rat),
+		currencyToSources:      make(map[string]RateSource),
+		currencyToHistory:      make(map[string][]RatePoint),
+		clock:                  systemClock{},
 	}
+	for _, option := range options {
+		option(db)
+	}
+	return db
 }
 
-func (db *database) AddUnitToRomanMapping(unit, roman string) {
-	db.unitToRomanValues[strings.ToLower(unit)] = strings.ToUpper(roman)
+func (db *database) addUnit(unit, roman string) error {
+	unit, roman = strings.ToLower(unit), strings.ToUpper(roman)
+	conflict := db.unitConflict(unit, roman)
+	if conflict != nil && db.policy == PolicyReject {
+		return conflict
+	}
+
+	db.unitToRomanValues[unit] = roman
+	return db.warn(conflict)
+}
+
+// unitConflict finds the definition changed by assigning roman to unit
+func (db *database) unitConflict(unit, roman string) error {
+	if current, exists := db.unitToRomanValues[unit]; exists {
+		if current == roman {
+			return nil
+		}
+		return &constant.ConflictError{Kind: "unit", Name: unit, Current: current}
+	}
+
+	if current, err := db.unitFromRoman(roman); err == nil {
+		return &constant.ConflictError{Kind: "symbol", Name: roman, Current: current}
+	}
+	return nil
+}
+
+// warn turns a conflict that was applied anyway into a warning, when asked to
+func (db *database) warn(conflict error) error {
+	if conflict == nil || db.policy == PolicyOverwrite {
+		return nil
+	}
+	return &constant.Warning{Err: conflict}
 }
 
 func (db *database) GetRomanFromUnit(unit string) (string, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	if roman, exists := db.unitToRomanValues[strings.ToLower(unit)]; exists {
 		return roman, nil
 	}
 
-	return "", errors.New(unit + " unit is not defined in the intergalactic database")
+	return "", &constant.UnknownUnitError{Unit: unit}
+}
+
+// GetUnitFromRoman is the reverse of GetRomanFromUnit. When several units are
+// assigned to the same roman symbol, the alphabetically first one is returned
+// so the answer does not depend on map iteration order.
+func (db *database) GetUnitFromRoman(roman string) (string, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.unitFromRoman(roman)
 }
 
-func (db *database) AddCurrencyToCreditsMapping(currency string, credits float32) {
-	db.currencyToCreditValues[strings.ToLower(currency)] = credits
+func (db *database) unitFromRoman(roman string) (string, error) {
+	roman = strings.ToUpper(roman)
+	found := ""
+	for unit, value := range db.unitToRomanValues {
+		if value == roman && (found == "" || unit < found) {
+			found = unit
+		}
+	}
+
+	if found == "" {
+		return "", &constant.UnassignedSymbolError{Symbol: roman}
+	}
+
+	return found, nil
+}
+
+func (db *database) removeUnit(unit string) error {
+	unit = strings.ToLower(unit)
+	if _, exists := db.unitToRomanValues[unit]; !exists {
+		return &constant.UnknownUnitError{Unit: unit}
+	}
+
+	delete(db.unitToRomanValues, unit)
+	return nil
+}
+
+// Credits are stored as exact rationals and copied on the way in and out,
+// so callers can never mutate the rate kept in the database.
+func (db *database) addCurrency(currency string, credits *big.Rat) error {
+	currency = strings.ToLower(currency)
+
+	var conflict error
+	if current, exists := db.currencyToCreditValues[currency]; exists && current.Cmp(credits) != 0 {
+		conflict = &constant.ConflictError{
+			Kind: "currency", Name: currency, Current: current.FloatString(2) + " Credits",
+		}
+	}
+	if conflict != nil && db.policy == PolicyReject {
+		return conflict
+	}
+
+	db.currencyToCreditValues[currency] = new(big.Rat).Set(credits)
+	db.record(currency, credits)
+	return db.warn(conflict)
+}
+
+func (db *database) GetCreditsFromCurrency(currency string) (*big.Rat, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.creditsFromCurrency(currency)
+}
+
+// GetCreditsFromCurrencies returns the rates of several currencies read at
+// once, so a change made meanwhile can not give them from different states
+func (db *database) GetCreditsFromCurrencies(currencies ...string) ([]*big.Rat, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rates := make([]*big.Rat, len(currencies))
+	for i, currency := range currencies {
+		credits, err := db.creditsFromCurrency(currency)
+		if err != nil {
+			return nil, err
+		}
+		rates[i] = credits
+	}
+	return rates, nil
+}
+
+// Read returns the symbols of units and the rates of currencies read at
+// once, so a query never combines the symbols of one state with rates computed
+// in another. The sources of the rates are read too, with the symbols of their
+// units.
+func (db *database) Read(units, currencies []string) Reading {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	reading := Reading{
+		Symbols: make(map[string]string, len(units)),
+		Rates:   make(map[string]*big.Rat, len(currencies)),
+		Sources: make(map[string]RateSource, len(currencies)),
+	}
+	units = append([]string(nil), units...)
+	for _, currency := range currencies {
+		currency = strings.ToLower(currency)
+		if credits, err := db.creditsFromCurrency(currency); err == nil {
+			reading.Rates[currency] = credits
+		}
+		if source, exists := db.currencyToSources[currency]; exists {
+			reading.Sources[currency] = copySource(source)
+			units = append(units, source.Units...)
+		}
+	}
+	for _, unit := range units {
+		unit = strings.ToLower(unit)
+		if roman, exists := db.unitToRomanValues[unit]; exists {
+			reading.Symbols[unit] = roman
+		}
+	}
+	return reading
 }
 
-func (db *database) GetCreditsFromCurrency(currency string) (float32, error) {
+func (db *database) creditsFromCurrency(currency string) (*big.Rat, error) {
 	if credits, exists := db.currencyToCreditValues[strings.ToLower(currency)]; exists {
-		return credits, nil
+		return new(big.Rat).Set(credits), nil
+	}
+
+	return nil, &constant.UnknownCurrencyError{Currency: currency}
+}
+
+// RemoveCurrency forgets the credits of a currency
+func (db *database) RemoveCurrency(currency string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.removeCurrency(currency)
+}
+
+func (db *database) removeCurrency(currency string) error {
+	currency = strings.ToLower(currency)
+	if _, exists := db.currencyToCreditValues[currency]; !exists {
+		return &constant.UnknownCurrencyError{Currency: currency}
+	}
+
+	delete(db.currencyToCreditValues, currency)
+	delete(db.currencyToSources, currency)
+	delete(db.currencyToHistory, currency)
+	return nil
+}
+
+// updateRate sets the rate of a defined currency with the sentence it was
+// computed from. It is how rates are kept in line with their units, so the
+// redefinition policy does not apply.
+func (db *database) updateRate(currency string, credits *big.Rat, source RateSource) error {
+	currency = strings.ToLower(currency)
+	if _, exists := db.currencyToCreditValues[currency]; !exists {
+		return &constant.UnknownCurrencyError{Currency: currency}
+	}
+
+	db.currencyToCreditValues[currency] = new(big.Rat).Set(credits)
+	db.record(currency, credits)
+	db.currencyToSources[currency] = copySource(source)
+	return nil
+}
+
+// DefineUnit assigns a roman symbol to a unit, and computes again with rate
+// every rate defined with the unit under the same lock, so the unit is never
+// seen with the rates of its previous symbol. The redefinition policy applies,
+// the stale currencies are returned along with the warning of the definition.
+func (db *database) DefineUnit(unit, roman string, rate RateFunc) ([]string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.defineUnit(unit, roman, rate)
+}
+
+func (db *database) defineUnit(unit, roman string, rate RateFunc) ([]string, error) {
+	err := db.addUnit(unit, roman)
+	if err != nil && !constant.IsWarning(err) {
+		return nil, err
+	}
+
+	stale, recalculateErr := db.recalculateRates(unit, rate)
+	if recalculateErr != nil {
+		return nil, recalculateErr
+	}
+	return stale, err
+}
+
+// ForgetUnit removes the symbol assigned to a unit, and flags stale the rates
+// defined with it under the same lock. The stale currencies are returned.
+func (db *database) ForgetUnit(unit string, rate RateFunc) ([]string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.forgetUnit(unit, rate)
+}
+
+func (db *database) forgetUnit(unit string, rate RateFunc) ([]string, error) {
+	if err := db.removeUnit(unit); err != nil {
+		return nil, err
+	}
+	return db.recalculateRates(unit, rate)
+}
+
+// DefineRate computes with rate the rate of a currency from the sentence
+// `{units} {currency} is {credits} Credits`, and sets it with its sentence so
+// it can follow later changes of its units. The symbols of the units are read
+// under the same lock the rate is set with. The redefinition policy applies,
+// the rate is returned along with the warning of the definition.
+func (db *database) DefineRate(currency string, source RateSource, rate RateFunc) (*big.Rat, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.defineRate(currency, source, rate)
+}
+
+func (db *database) defineRate(currency string, source RateSource, rate RateFunc) (*big.Rat, error) {
+	credits, err := db.rateOf(source, rate)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.addCurrency(currency, credits)
+	if err != nil && !constant.IsWarning(err) {
+		return nil, err
+	}
+	db.currencyToSources[strings.ToLower(currency)] = copySource(source)
+	return credits, err
+}
+
+// recalculateRates computes again with rate every rate defined with the unit,
+// from the symbols the units of its sentence are assigned to now. A rate that
+// can not be computed any more, e.g. because the unit was removed, keeps its
+// last value and is flagged stale. The stale currencies are returned, sorted.
+func (db *database) recalculateRates(unit string, rate RateFunc) ([]string, error) {
+	stale := make([]string, 0)
+	for _, currency := range db.currenciesUsingUnit(unit) {
+		source := copySource(db.currencyToSources[currency])
+		credits, err := db.rateOf(source, rate)
+		if err != nil {
+			credits = db.currencyToCreditValues[currency]
+			source.Stale = true
+			stale = append(stale, currency)
+		} else {
+			source.Stale = false
+		}
+
+		if err := db.updateRate(currency, credits, source); err != nil {
+			return nil, err
+		}
+	}
+
+	return stale, nil
+}
+
+// rateOf computes the rate of a sentence with the symbols its units have now
+func (db *database) rateOf(source RateSource, rate RateFunc) (*big.Rat, error) {
+	symbols := make([]string, len(source.Units))
+	for i, unit := range source.Units {
+		unit = strings.ToLower(unit)
+		symbol, exists := db.unitToRomanValues[unit]
+		if !exists {
+			return nil, &constant.UnknownUnitError{Unit: unit}
+		}
+		symbols[i] = symbol
+	}
+	return rate(symbols, source.Credits)
+}
+
+// GetRateSource returns the sentence a rate was computed from, a currency
+// defined without one is reported as unknown
+func (db *database) GetRateSource(currency string) (RateSource, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if source, exists := db.currencyToSources[strings.ToLower(currency)]; exists {
+		return copySource(source), nil
+	}
+
+	return RateSource{}, &constant.UnknownCurrencyError{Currency: currency}
+}
+
+// currenciesUsingUnit returns every currency whose rate was computed with the
+// unit, sorted
+func (db *database) currenciesUsingUnit(unit string) []string {
+	unit = strings.ToLower(unit)
+	currencies := make([]string, 0)
+	for currency, source := range db.currencyToSources {
+		for _, used := range source.Units {
+			if strings.ToLower(used) == unit {
+				currencies = append(currencies, currency)
+				break
+			}
+		}
+	}
+	sort.Strings(currencies)
+
+	return currencies
+}
+
+func copySource(source RateSource) RateSource {
+	units := append([]string(nil), source.Units...)
+	credits := new(big.Rat)
+	if source.Credits != nil {
+		credits.Set(source.Credits)
+	}
+	return RateSource{Units: units, Credits: credits, Stale: source.Stale}
+}
+
+// ListUnits returns every unit with a roman symbol assigned, sorted
+func (db *database) ListUnits() []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	units := make([]string, 0, len(db.unitToRomanValues))
+	for unit := range db.unitToRomanValues {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+
+	return units
+}
+
+// ListCurrencies returns every currency with credits defined, sorted
+func (db *database) ListCurrencies() []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	currencies := make([]string, 0, len(db.currencyToCreditValues))
+	for currency := range db.currencyToCreditValues {
+		currencies = append(currencies, currency)
 	}
+	sort.Strings(currencies)
 
-	return 0, errors.New(currency + " currency is not defined in the intergalactic database")
+	return currencies
 }
@@ -1,7 +1,14 @@
 package database
 
 import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sync"
 	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
 )
 
 func TestNewDatabase(t *testing.T) {
@@ -11,9 +18,9 @@ func TestNewDatabase(t *testing.T) {
 	}
 }
 
-func TestAddUnitToRomanMapping(t *testing.T) {
+func TestDefineUnit(t *testing.T) {
 	db := NewDatabase()
-	db.AddUnitToRomanMapping("xyz", "I")
+	db.DefineUnit("xyz", "I", sumRate)
 
 	roman, err := db.GetRomanFromUnit("xyz")
 	if err != nil {
@@ -26,7 +33,7 @@ func TestAddUnitToRomanMapping(t *testing.T) {
 
 func TestGetRomanFromUnit(t *testing.T) {
 	db := NewDatabase()
-	db.AddUnitToRomanMapping("abc", "V")
+	db.DefineUnit("abc", "V", sumRate)
 
 	tests := []struct {
 		input    string
@@ -52,31 +59,70 @@ func TestGetRomanFromUnit(t *testing.T) {
 	}
 }
 
-func TestAddCurrencyToCreditsMapping(t *testing.T) {
+func TestGetUnitFromRoman(t *testing.T) {
+	db := NewDatabase()
+	db.DefineUnit("xyz", "V", sumRate)
+	db.DefineUnit("abc", "V", sumRate)
+	db.DefineUnit("def", "X", sumRate)
+
+	tests := []struct {
+		input    string
+		expected string
+		hasError bool
+	}{
+		{"V", "abc", false},
+		{"x", "def", false},
+		{"M", "", true},
+	}
+
+	for _, test := range tests {
+		result, err := db.GetUnitFromRoman(test.input)
+		if test.hasError && err == nil {
+			t.Errorf("Expected error for input '%s', got none", test.input)
+		}
+		if !test.hasError && err != nil {
+			t.Errorf("Unexpected error for input '%s': %v", test.input, err)
+		}
+		if result != test.expected {
+			t.Errorf("For input '%s', expected '%s', got '%s'", test.input, test.expected, result)
+		}
+	}
+}
+
+func TestDefineRateCopiesCredits(t *testing.T) {
 	db := NewDatabase()
-	db.AddCurrencyToCreditsMapping("Gold", 14450.0)
+	db.DefineUnit("glob", "I", sumRate)
+	db.DefineRate("Gold", RateSource{Units: []string{"glob"}, Credits: big.NewRat(14450, 1)}, sumRate)
 
 	credits, err := db.GetCreditsFromCurrency("Gold")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
-	if credits != 14450.0 {
-		t.Errorf("Expected 14450.0, got %f", credits)
+	if credits.Cmp(big.NewRat(14450, 1)) != 0 {
+		t.Errorf("Expected 14450, got %s", credits.RatString())
+	}
+
+	// Mutating the returned value must not change the stored rate
+	credits.SetInt64(1)
+	credits, _ = db.GetCreditsFromCurrency("Gold")
+	if credits.Cmp(big.NewRat(14450, 1)) != 0 {
+		t.Errorf("Expected stored rate to be unchanged, got %s", credits.RatString())
 	}
 }
 
 func TestGetCreditsFromCurrency(t *testing.T) {
 	db := NewDatabase()
-	db.AddCurrencyToCreditsMapping("Silver", 17.0)
+	db.DefineUnit("glob", "I", sumRate)
+	db.DefineRate("Silver", RateSource{Units: []string{"glob"}, Credits: big.NewRat(17, 1)}, sumRate)
 
 	tests := []struct {
 		input    string
-		expected float32
+		expected *big.Rat
 		hasError bool
 	}{
-		{"Silver", 17.0, false},
-		{"SILVER", 17.0, false},
-		{"unknown", 0, true},
+		{"Silver", big.NewRat(17, 1), false},
+		{"SILVER", big.NewRat(17, 1), false},
+		{"unknown", nil, true},
 	}
 
 	for _, test := range tests {
@@ -87,8 +133,295 @@ func TestGetCreditsFromCurrency(t *testing.T) {
 		if !test.hasError && err != nil {
 			t.Errorf("Unexpected error for input '%s': %v", test.input, err)
 		}
-		if result != test.expected {
-			t.Errorf("For input '%s', expected %f, got %f", test.input, test.expected, result)
+		if (result == nil) != (test.expected == nil) || (result != nil && result.Cmp(test.expected) != 0) {
+			t.Errorf("For input '%s', expected %v, got %v", test.input, test.expected, result)
+		}
+	}
+}
+
+func TestListUnitsAndCurrencies(t *testing.T) {
+	db := NewDatabase()
+	if len(db.ListUnits()) != 0 || len(db.ListCurrencies()) != 0 {
+		t.Errorf("Expected an empty database")
+	}
+
+	db.DefineUnit("prok", "V", sumRate)
+	db.DefineUnit("Glob", "I", sumRate)
+	db.DefineRate("Silver", RateSource{Units: []string{"glob"}, Credits: big.NewRat(17, 1)}, sumRate)
+	db.DefineRate("gold", RateSource{Units: []string{"glob"}, Credits: big.NewRat(14450, 1)}, sumRate)
+
+	if units := db.ListUnits(); !reflect.DeepEqual(units, []string{"glob", "prok"}) {
+		t.Errorf("Expected [glob prok], got %v", units)
+	}
+	if currencies := db.ListCurrencies(); !reflect.DeepEqual(currencies, []string{"gold", "silver"}) {
+		t.Errorf("Expected [gold silver], got %v", currencies)
+	}
+}
+
+func TestDatabaseErrors(t *testing.T) {
+	db := NewDatabase()
+
+	_, err := db.GetRomanFromUnit("xyz")
+	var unitErr *constant.UnknownUnitError
+	if !errors.As(err, &unitErr) || unitErr.Unit != "xyz" {
+		t.Errorf("Expected UnknownUnitError for xyz, got %v", err)
+	}
+
+	_, err = db.GetUnitFromRoman("m")
+	var symbolErr *constant.UnassignedSymbolError
+	if !errors.As(err, &symbolErr) || symbolErr.Symbol != "M" {
+		t.Errorf("Expected UnassignedSymbolError for M, got %v", err)
+	}
+
+	_, err = db.GetCreditsFromCurrency("Gold")
+	var currencyErr *constant.UnknownCurrencyError
+	if !errors.As(err, &currencyErr) || currencyErr.Currency != "Gold" {
+		t.Errorf("Expected UnknownCurrencyError for Gold, got %v", err)
+	}
+}
+
+func TestForgetUnitAndRemoveCurrency(t *testing.T) {
+	db := NewDatabase()
+	db.DefineUnit("glob", "I", sumRate)
+	db.DefineRate("silver", RateSource{Units: []string{"glob"}, Credits: big.NewRat(17, 1)}, sumRate)
+
+	if err := db.RemoveCurrency("Silver"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := db.ForgetUnit("Glob", sumRate); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(db.ListUnits()) != 0 || len(db.ListCurrencies()) != 0 {
+		t.Errorf("Expected an empty database, got %v %v", db.ListUnits(), db.ListCurrencies())
+	}
+
+	if _, err := db.ForgetUnit("glob", sumRate); !errors.Is(err, constant.ErrNotDefined) {
+		t.Errorf("Expected ErrNotDefined for glob, got %v", err)
+	}
+	if err := db.RemoveCurrency("silver"); !errors.Is(err, constant.ErrNotDefined) {
+		t.Errorf("Expected ErrNotDefined for silver, got %v", err)
+	}
+}
+
+func TestRedefinitionPolicy(t *testing.T) {
+	tests := []struct {
+		policy   Policy
+		expected string
+		warning  bool
+		conflict bool
+	}{
+		{PolicyWarn, "V", true, false},
+		{PolicyReject, "I", false, true},
+		{PolicyOverwrite, "V", false, false},
+	}
+
+	for _, test := range tests {
+		db := NewDatabase(WithPolicy(test.policy))
+		db.DefineUnit("glob", "I", sumRate)
+		db.DefineUnit("prok", "X", sumRate)
+
+		// Same definition again is never a conflict
+		if _, err := db.DefineUnit("glob", "I", sumRate); err != nil {
+			t.Errorf("Unexpected error for policy %d: %v", test.policy, err)
+		}
+
+		_, err := db.DefineUnit("glob", "V", sumRate)
+		if constant.IsWarning(err) != test.warning {
+			t.Errorf("IsWarning(%v) = %v for policy %d", err, !test.warning, test.policy)
+		}
+		if errors.Is(err, constant.ErrAlreadyDefined) != (test.warning || test.conflict) {
+			t.Errorf("Unexpected error for policy %d: %v", test.policy, err)
+		}
+		if roman, _ := db.GetRomanFromUnit("glob"); roman != test.expected {
+			t.Errorf("Expected %s for policy %d, got %s", test.expected, test.policy, roman)
+		}
+
+		var conflict *constant.ConflictError
+		_, err = db.DefineUnit("pish", "X", sumRate)
+		if (test.warning || test.conflict) && (!errors.As(err, &conflict) || conflict.Kind != "symbol" || conflict.Current != "prok") {
+			t.Errorf("Expected a symbol conflict with prok for policy %d, got %v", test.policy, err)
+		}
+
+		db.DefineRate("silver", RateSource{Units: []string{"prok"}, Credits: big.NewRat(170, 1)}, sumRate)
+		_, err = db.DefineRate("silver", RateSource{Units: []string{"prok"}, Credits: big.NewRat(180, 1)}, sumRate)
+		if errors.Is(err, constant.ErrAlreadyDefined) != (test.warning || test.conflict) {
+			t.Errorf("Unexpected currency error for policy %d: %v", test.policy, err)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for name, expected := range map[string]Policy{"warn": PolicyWarn, "Reject": PolicyReject, "overwrite": PolicyOverwrite} {
+		if policy, err := ParsePolicy(name); err != nil || policy != expected {
+			t.Errorf("ParsePolicy(%s) = %d, %v, want %d", name, policy, err, expected)
+		}
+	}
+	if _, err := ParsePolicy("ignore"); err == nil {
+		t.Errorf("Expected an error for ignore")
+	}
+}
+
+// sumRate divides the credits by the sum of the symbols, enough for the
+// single symbol sentences of the tests
+func sumRate(symbols []string, credits *big.Rat) (*big.Rat, error) {
+	values := map[string]int64{"I": 1, "V": 5, "X": 10}
+	sum := int64(0)
+	for _, symbol := range symbols {
+		sum += values[symbol]
+	}
+	if sum == 0 {
+		return nil, constant.ErrInvalidFormat
+	}
+	return new(big.Rat).Quo(credits, big.NewRat(sum, 1)), nil
+}
+
+func TestDefineRate(t *testing.T) {
+	db := NewDatabase(WithPolicy(PolicyReject))
+	db.DefineUnit("glob", "I", sumRate)
+	db.DefineUnit("prok", "V", sumRate)
+	source := RateSource{Units: []string{"Glob", "glob"}, Credits: big.NewRat(34, 1)}
+
+	rate, err := db.DefineRate("Silver", source, sumRate)
+	if err != nil || rate.Cmp(big.NewRat(17, 1)) != 0 {
+		t.Fatalf("Expected 17, got %v (%v)", rate, err)
+	}
+	credits, err := db.GetCreditsFromCurrency("silver")
+	if err != nil || credits.Cmp(big.NewRat(17, 1)) != 0 {
+		t.Errorf("Expected 17, got %v (%v)", credits, err)
+	}
+	got, err := db.GetRateSource("silver")
+	if err != nil || !reflect.DeepEqual(got.Units, source.Units) || got.Credits.Cmp(source.Credits) != 0 {
+		t.Errorf("Expected %v, got %v (%v)", source, got, err)
+	}
+
+	// A rejected redefinition keeps both the rate and its sentence
+	other := RateSource{Units: []string{"prok"}, Credits: big.NewRat(90, 1)}
+	if _, err := db.DefineRate("silver", other, sumRate); !errors.Is(err, constant.ErrAlreadyDefined) {
+		t.Errorf("Expected ErrAlreadyDefined, got %v", err)
+	}
+	got, _ = db.GetRateSource("silver")
+	if !reflect.DeepEqual(got.Units, source.Units) {
+		t.Errorf("Expected %v to be kept, got %v", source.Units, got.Units)
+	}
+
+	// A rate that can not be computed is not defined
+	unknown := RateSource{Units: []string{"pish"}, Credits: big.NewRat(10, 1)}
+	if _, err := db.DefineRate("gold", unknown, sumRate); !errors.Is(err, constant.ErrNotDefined) {
+		t.Errorf("Expected ErrNotDefined for pish, got %v", err)
+	}
+	if _, err := db.GetCreditsFromCurrency("gold"); err == nil {
+		t.Error("Expected gold not to be defined")
+	}
+}
+
+func TestDefineAndForgetUnit(t *testing.T) {
+	db := NewDatabase()
+	db.DefineUnit("xyz", "I", sumRate)
+	db.DefineUnit("abc", "V", sumRate)
+	db.DefineRate("silver", RateSource{Units: []string{"xyz", "xyz"}, Credits: big.NewRat(34, 1)}, sumRate)
+	db.DefineRate("gold", RateSource{Units: []string{"abc", "xyz"}, Credits: big.NewRat(86700, 1)}, sumRate)
+	db.DefineRate("iron", RateSource{Units: []string{"abc"}, Credits: big.NewRat(975, 1)}, sumRate)
+
+	stale, err := db.DefineUnit("XYZ", "X", sumRate)
+	if err == nil || !constant.IsWarning(err) {
+		t.Errorf("Expected a warning for the redefinition, got %v", err)
+	}
+	if len(stale) != 0 {
+		t.Errorf("Expected no stale currency, got %v", stale)
+	}
+
+	// Without abc, gold and iron can not be computed any more and keep their rate
+	stale, err = db.ForgetUnit("abc", sumRate)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(stale, []string{"gold", "iron"}) {
+		t.Errorf("Expected [gold iron] to be stale, got %v", stale)
+	}
+	if _, err := db.ForgetUnit("abc", sumRate); !errors.Is(err, constant.ErrNotDefined) {
+		t.Errorf("Expected ErrNotDefined for abc, got %v", err)
+	}
+
+	tests := []struct {
+		currency string
+		expected *big.Rat
+		stale    bool
+	}{
+		{"silver", big.NewRat(17, 10), false},
+		{"gold", big.NewRat(5780, 1), true},
+		{"iron", big.NewRat(195, 1), true},
+	}
+
+	for _, test := range tests {
+		credits, _ := db.GetCreditsFromCurrency(test.currency)
+		source, _ := db.GetRateSource(test.currency)
+		if credits.Cmp(test.expected) != 0 || source.Stale != test.stale {
+			t.Errorf("For %s, expected %v stale %v, got %v stale %v", test.currency, test.expected, test.stale, credits, source.Stale)
 		}
 	}
 }
+
+func TestDatabaseConcurrentAccess(t *testing.T) {
+	db := NewDatabase(WithPolicy(PolicyOverwrite))
+	db.DefineUnit("base", "I", sumRate)
+	db.DefineRate("copper", RateSource{Units: []string{"base"}, Credits: big.NewRat(1, 1)}, sumRate)
+	// gold and silver follow the shared unit, gold is always worth twice silver
+	db.DefineUnit("shared", "I", sumRate)
+	db.DefineRate("gold", RateSource{Units: []string{"shared"}, Credits: big.NewRat(60, 1)}, sumRate)
+	db.DefineRate("silver", RateSource{Units: []string{"shared"}, Credits: big.NewRat(30, 1)}, sumRate)
+
+	const workers, rounds = 8, 200
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				unit := fmt.Sprintf("unit%d", w)
+				db.DefineUnit(unit, "IVXLCDM"[i%7:i%7+1], sumRate)
+				db.DefineRate("copper", RateSource{Units: []string{"base"}, Credits: big.NewRat(int64(w*rounds+i+1), 1)}, sumRate)
+				db.DefineRate("copper", RateSource{Units: []string{unit}, Credits: big.NewRat(int64(i+1), 1)}, sumRate)
+				db.DefineUnit("shared", "IVX"[(w+i)%3:(w+i)%3+1], sumRate)
+				db.DefineRate("silver", RateSource{Units: []string{"shared"}, Credits: big.NewRat(30, 1)}, sumRate)
+				if i%10 == 0 {
+					db.ForgetUnit(unit, sumRate)
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				// The unit and the rates following it are read as they were
+				// changed, at once
+				snapshot := db.Snapshot(Scope{Units: []string{"shared"}})
+				expected, _ := sumRate([]string{snapshot.units["shared"]}, big.NewRat(30, 1))
+				gold, silver := snapshot.currencies["gold"], snapshot.currencies["silver"]
+				if silver.Cmp(expected) != 0 || gold.Cmp(new(big.Rat).Mul(expected, big.NewRat(2, 1))) != 0 {
+					t.Errorf("Expected gold and silver to follow %s, got %v and %v", snapshot.units["shared"], gold, silver)
+					return
+				}
+				reading := db.Read([]string{"shared"}, []string{"silver"})
+				expected, _ = sumRate([]string{reading.Symbols["shared"]}, big.NewRat(30, 1))
+				if reading.Rates["silver"].Cmp(expected) != 0 {
+					t.Errorf("Expected silver to follow %s, got %v", reading.Symbols["shared"], reading.Rates["silver"])
+					return
+				}
+				if _, err := db.GetCreditsFromCurrencies("gold", "silver"); err != nil {
+					t.Errorf("Unexpected error: %v", err)
+					return
+				}
+				db.ListUnits()
+				db.ListCurrencies()
+				db.GetUnitFromRoman("I")
+				db.GetRateSource("copper")
+				db.GetRateHistory("gold")
+			}
+		}()
+	}
+	wg.Wait()
+
+	history, err := db.GetRateHistory("copper")
+	if err != nil || len(history) == 0 {
+		t.Errorf("Expected a history for copper, got %v (%v)", history, err)
+	}
+}
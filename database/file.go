@@ -0,0 +1,290 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+// fileDatabase keeps the same in-memory mapping as database, and writes the
+// whole mapping to a JSON file after every change. The lock of database is
+// held while saving, so files are written one at a time and never with a
+// change that is rolled back. While a journal holds the writes back, changes
+// are only noted and the file is written once the step ends.
+type fileDatabase struct {
+	*database
+	path string
+	// holds counts the steps in progress, dirty notes a change held back,
+	// both are guarded by the lock of database
+	holds int
+	dirty bool
+}
+
+// fileContent is the on-disk layout. Credits are kept as rational strings
+// (e.g. "391/2") so no precision is lost between sessions.
+type fileContent struct {
+	Units      map[string]string         `json:"units"`
+	Currencies map[string]string         `json:"currencies"`
+	Sources    map[string]sourceContent  `json:"sources,omitempty"`
+	History    map[string][]pointContent `json:"history,omitempty"`
+}
+
+type sourceContent struct {
+	Units   []string `json:"units"`
+	Credits string   `json:"credits"`
+	Stale   bool     `json:"stale,omitempty"`
+}
+
+type pointContent struct {
+	Time    time.Time `json:"time"`
+	Credits string    `json:"credits"`
+}
+
+// NewFileDatabase opens the database stored at path, starting empty when the
+// file does not exist yet.
+func NewFileDatabase(path string, options ...Option) (Database, error) {
+	db := &fileDatabase{
+		database: newDatabase(options),
+		path:     path,
+	}
+
+	if err := db.load(); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (db *fileDatabase) DefineUnit(unit, roman string, rate RateFunc) ([]string, error) {
+	var stale []string
+	err := db.change(func() (err error) {
+		stale, err = db.database.defineUnit(unit, roman, rate)
+		return err
+	})
+	return stale, err
+}
+
+func (db *fileDatabase) ForgetUnit(unit string, rate RateFunc) ([]string, error) {
+	var stale []string
+	err := db.change(func() (err error) {
+		stale, err = db.database.forgetUnit(unit, rate)
+		return err
+	})
+	return stale, err
+}
+
+func (db *fileDatabase) DefineRate(currency string, source RateSource, rate RateFunc) (*big.Rat, error) {
+	var credits *big.Rat
+	err := db.change(func() (err error) {
+		credits, err = db.database.defineRate(currency, source, rate)
+		return err
+	})
+	return credits, err
+}
+
+// change applies a change of several parts of the mapping and saves it,
+// holding the lock for both. The previous state comes back when either
+// fails, a warning is a change that was applied.
+func (db *fileDatabase) change(apply func() error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	previous := db.database.snapshot()
+	err := apply()
+	if err != nil && !constant.IsWarning(err) {
+		db.database.restore(previous)
+		return err
+	}
+
+	if saveErr := db.save(); saveErr != nil {
+		db.database.restore(previous)
+		return saveErr
+	}
+
+	return err
+}
+
+func (db *fileDatabase) RemoveCurrency(currency string) error {
+	return db.change(func() error {
+		return db.database.removeCurrency(currency)
+	})
+}
+
+func (db *fileDatabase) Restore(snapshot Snapshot) error {
+	return db.change(func() error {
+		db.database.restore(snapshot)
+		return nil
+	})
+}
+
+func (db *fileDatabase) load() error {
+	data, err := os.ReadFile(db.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var content fileContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return fmt.Errorf("%s is not a valid intergalactic database: %w", db.path, err)
+	}
+
+	for unit, roman := range content.Units {
+		db.unitToRomanValues[strings.ToLower(unit)] = strings.ToUpper(roman)
+	}
+	for currency, value := range content.Currencies {
+		credits, ok := new(big.Rat).SetString(value)
+		if !ok {
+			return fmt.Errorf("%s is not a valid intergalactic database: %s has invalid credits %q", db.path, currency, value)
+		}
+		db.currencyToCreditValues[strings.ToLower(currency)] = credits
+	}
+	for currency, source := range content.Sources {
+		credits, ok := new(big.Rat).SetString(source.Credits)
+		if !ok {
+			return fmt.Errorf("%s is not a valid intergalactic database: %s has invalid source credits %q", db.path, currency, source.Credits)
+		}
+		db.currencyToSources[strings.ToLower(currency)] = RateSource{Units: source.Units, Credits: credits, Stale: source.Stale}
+	}
+	for currency, points := range content.History {
+		history := make([]RatePoint, len(points))
+		for i, point := range points {
+			credits, ok := new(big.Rat).SetString(point.Credits)
+			if !ok {
+				return fmt.Errorf("%s is not a valid intergalactic database: %s has invalid history credits %q", db.path, currency, point.Credits)
+			}
+			history[i] = RatePoint{Time: point.Time.UTC(), Credits: credits}
+		}
+		db.currencyToHistory[strings.ToLower(currency)] = history
+	}
+	// Rates saved before history was kept are known since ever
+	for currency, credits := range db.currencyToCreditValues {
+		if _, recorded := db.currencyToHistory[currency]; !recorded {
+			db.currencyToHistory[currency] = []RatePoint{{Credits: new(big.Rat).Set(credits)}}
+		}
+	}
+
+	return nil
+}
+
+// hold defers writing the file until every hold is released
+func (db *fileDatabase) hold() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.holds++
+}
+
+// release ends a hold, the changes held back are written once none is left
+func (db *fileDatabase) release() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.holds--
+	if db.holds > 0 || !db.dirty {
+		return nil
+	}
+	return db.save()
+}
+
+// save writes the whole mapping, or notes it has to be written while a hold
+// is in progress. A change held back can not fail to save, it stays in memory
+// and is written with the next save.
+func (db *fileDatabase) save() error {
+	if db.holds > 0 {
+		db.dirty = true
+		return nil
+	}
+	if err := db.write(); err != nil {
+		return err
+	}
+	db.dirty = false
+	return nil
+}
+
+func (db *fileDatabase) write() error {
+	content := fileContent{
+		Units:      db.unitToRomanValues,
+		Currencies: make(map[string]string, len(db.currencyToCreditValues)),
+	}
+	for currency, credits := range db.currencyToCreditValues {
+		content.Currencies[currency] = credits.RatString()
+	}
+	if len(db.currencyToSources) > 0 {
+		content.Sources = make(map[string]sourceContent, len(db.currencyToSources))
+		for currency, source := range db.currencyToSources {
+			content.Sources[currency] = sourceContent{
+				Units:   source.Units,
+				Credits: source.Credits.RatString(),
+				Stale:   source.Stale,
+			}
+		}
+	}
+	if len(db.currencyToHistory) > 0 {
+		content.History = make(map[string][]pointContent, len(db.currencyToHistory))
+		for currency, history := range db.currencyToHistory {
+			points := make([]pointContent, len(history))
+			for i, point := range history {
+				points[i] = pointContent{Time: point.Time, Credits: point.Credits.RatString()}
+			}
+			content.History[currency] = points
+		}
+	}
+
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(db.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so a crash mid-write leaves either the old or the new
+// file but never a partial one.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	// Removing is a no-op once the rename succeeded
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself, not every platform supports syncing a directory
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+package database
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+func TestNewFileDatabaseMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+
+	db, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := db.GetRomanFromUnit("xyz"); err == nil {
+		t.Error("Expected empty database, got a unit")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no file before the first change, got %v", err)
+	}
+}
+
+func TestFileDatabasePersists(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db.json")
+
+	db, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := db.DefineUnit("xyz", "i", sumRate); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := db.DefineUnit("pish", "X", sumRate); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	source := RateSource{Units: []string{"pish", "pish"}, Credits: big.NewRat(3910, 1)}
+	if _, err := db.DefineRate("Iron", source, sumRate); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Without pish the rate is kept and flagged stale
+	if _, err := db.ForgetUnit("pish", sumRate); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	reopened, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	roman, err := reopened.GetRomanFromUnit("XYZ")
+	if err != nil || roman != "I" {
+		t.Errorf("Expected 'I', got '%s' (%v)", roman, err)
+	}
+	credits, err := reopened.GetCreditsFromCurrency("iron")
+	if err != nil || credits.Cmp(big.NewRat(391, 2)) != 0 {
+		t.Errorf("Expected 391/2, got %v (%v)", credits, err)
+	}
+
+	reloaded, err := reopened.GetRateSource("iron")
+	if err != nil || !reflect.DeepEqual(reloaded.Units, source.Units) || reloaded.Credits.Cmp(source.Credits) != 0 || !reloaded.Stale {
+		t.Errorf("Expected %v, got %v (%v)", source, reloaded, err)
+	}
+
+	// Only the database itself should be left behind, no temporary files
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only db.json in %s, got %d entries", dir, len(entries))
+	}
+}
+
+func TestFileDatabaseRemovesAndRedefines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+
+	db, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	db.DefineUnit("xyz", "I", sumRate)
+	db.DefineRate("iron", RateSource{Units: []string{"xyz"}, Credits: big.NewRat(391, 2)}, sumRate)
+
+	// A warning is still a change, it has to be saved too
+	if _, err := db.DefineUnit("xyz", "V", sumRate); !constant.IsWarning(err) {
+		t.Errorf("Expected a warning, got %v", err)
+	}
+	if err := db.RemoveCurrency("iron"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	reopened, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if roman, err := reopened.GetRomanFromUnit("xyz"); err != nil || roman != "V" {
+		t.Errorf("Expected 'V', got '%s' (%v)", roman, err)
+	}
+	if _, err := reopened.GetCreditsFromCurrency("iron"); err == nil {
+		t.Error("Expected iron to be removed")
+	}
+}
+
+func TestNewFileDatabaseInvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"not json", "glob is I"},
+		{"invalid credits", `{"units":{},"currencies":{"gold":"lots"}}`},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "db.json")
+		if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, err := NewFileDatabase(path); err == nil {
+			t.Errorf("Expected error for %s, got none", test.name)
+		}
+	}
+}
+
+func TestFileDatabaseSaveFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "db.json")
+
+	db, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := db.DefineUnit("xyz", "I", sumRate); err == nil {
+		t.Error("Expected error when the directory does not exist, got none")
+	}
+	if _, err := db.GetRomanFromUnit("xyz"); err == nil {
+		t.Error("Expected failed change not to be kept in memory")
+	}
+	source := RateSource{Units: []string{"xyz"}, Credits: big.NewRat(1, 1)}
+	if _, err := db.DefineRate("gold", source, sumRate); err == nil {
+		t.Error("Expected an error, got none")
+	}
+	if _, err := db.GetRateSource("gold"); err == nil {
+		t.Error("Expected failed change not to be kept in memory")
+	}
+}
+
+func TestFileDatabaseSavesSteps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	journal := NewJournal(db)
+
+	saved := func() []string {
+		reopened, err := NewFileDatabase(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return reopened.ListUnits()
+	}
+
+	// A statement is written once it ends
+	journal.DefineUnit("glob", "I", sumRate)
+	if units := saved(); len(units) != 0 {
+		t.Errorf("Expected nothing saved before the statement ends, got %v", units)
+	}
+	if err := journal.EndStatement(false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if units := saved(); !reflect.DeepEqual(units, []string{"glob"}) {
+		t.Errorf("Expected [glob] saved, got %v", units)
+	}
+
+	// A block is written once it is committed, never when rolled back
+	journal.Begin()
+	journal.DefineUnit("prok", "V", sumRate)
+	journal.EndStatement(false)
+	if units := saved(); !reflect.DeepEqual(units, []string{"glob"}) {
+		t.Errorf("Expected the block not to be saved yet, got %v", units)
+	}
+	if err := journal.Rollback(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if units := saved(); !reflect.DeepEqual(units, []string{"glob"}) {
+		t.Errorf("Expected [glob] saved, got %v", units)
+	}
+
+	journal.Begin()
+	journal.DefineUnit("prok", "V", sumRate)
+	journal.EndStatement(false)
+	journal.DefineUnit("pish", "X", sumRate)
+	journal.EndStatement(false)
+	if err := journal.Commit(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if units := saved(); !reflect.DeepEqual(units, []string{"glob", "pish", "prok"}) {
+		t.Errorf("Expected the block saved, got %v", units)
+	}
+}
+
+func TestFileDatabaseConcurrentAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, WithPolicy(PolicyOverwrite))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	db.DefineUnit("base", "I", sumRate)
+
+	const workers, rounds = 4, 25
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				unit := fmt.Sprintf("unit%d", w)
+				db.DefineUnit(unit, "IVXLCDM"[i%7:i%7+1], sumRate)
+				db.DefineRate(fmt.Sprintf("currency%d", w), RateSource{Units: []string{"base"}, Credits: big.NewRat(int64(i+1), 1)}, sumRate)
+				db.GetCreditsFromCurrencies(fmt.Sprintf("currency%d", w))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// Saves are never interleaved, the file holds the last state
+	reopened, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(reopened.ListUnits(), db.ListUnits()) || !reflect.DeepEqual(reopened.ListCurrencies(), db.ListCurrencies()) {
+		t.Errorf("Expected %v %v, got %v %v", db.ListUnits(), db.ListCurrencies(), reopened.ListUnits(), reopened.ListCurrencies())
+	}
+	for _, currency := range db.ListCurrencies() {
+		credits, _ := reopened.GetCreditsFromCurrency(currency)
+		if credits == nil || credits.Cmp(big.NewRat(rounds, 1)) != 0 {
+			t.Errorf("Expected %s to be worth %d, got %v", currency, rounds, credits)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+// Clock tells the time rates are recorded at, it is replaced in tests
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock records rates at the time told by clock instead of the system time
+func WithClock(clock Clock) Option {
+	return func(db *database) {
+		db.clock = clock
+	}
+}
+
+// RatePoint is the rate of a currency from Time on, until the next point
+type RatePoint struct {
+	Time    time.Time
+	Credits *big.Rat
+}
+
+// record appends the rate to the history of the currency, unless it did not
+// change since the last point
+func (db *database) record(currency string, credits *big.Rat) {
+	history := db.currencyToHistory[currency]
+	if len(history) > 0 && history[len(history)-1].Credits.Cmp(credits) == 0 {
+		return
+	}
+
+	point := RatePoint{Time: db.clock.Now().UTC(), Credits: new(big.Rat).Set(credits)}
+	db.currencyToHistory[currency] = append(history, point)
+}
+
+// GetCreditsFromCurrencyAt returns the rate a currency had at the given time
+func (db *database) GetCreditsFromCurrencyAt(currency string, at time.Time) (*big.Rat, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	history, exists := db.currencyToHistory[strings.ToLower(currency)]
+	if !exists {
+		return nil, &constant.UnknownCurrencyError{Currency: currency}
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].Time.After(at) {
+			return new(big.Rat).Set(history[i].Credits), nil
+		}
+	}
+
+	return nil, &constant.NoRateError{Currency: currency, At: at}
+}
+
+// GetRateHistory returns every rate a currency had, oldest first
+func (db *database) GetRateHistory(currency string) ([]RatePoint, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	history, exists := db.currencyToHistory[strings.ToLower(currency)]
+	if !exists {
+		return nil, &constant.UnknownCurrencyError{Currency: currency}
+	}
+
+	points := make([]RatePoint, len(history))
+	for i, point := range history {
+		points[i] = RatePoint{Time: point.Time, Credits: new(big.Rat).Set(point.Credits)}
+	}
+	return points, nil
+}
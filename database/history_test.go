@@ -0,0 +1,129 @@
+package database
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+// fakeClock tells a fixed time, moved by the tests
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestRateHistory(t *testing.T) {
+	january := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: january}
+	db := NewDatabase(WithClock(clock), WithPolicy(PolicyOverwrite))
+
+	db.DefineUnit("glob", "I", sumRate)
+	db.DefineRate("gold", RateSource{Units: []string{"glob"}, Credits: big.NewRat(14450, 1)}, sumRate)
+	clock.now = january.Add(time.Hour)
+	// The same rate again is not a new point
+	db.DefineRate("Gold", RateSource{Units: []string{"glob"}, Credits: big.NewRat(14450, 1)}, sumRate)
+	clock.now = march
+	db.DefineRate("gold", RateSource{Units: []string{"glob"}, Credits: big.NewRat(15000, 1)}, sumRate)
+
+	history, err := db.GetRateHistory("GOLD")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(history) != 2 || !history[0].Time.Equal(january) || !history[1].Time.Equal(march) ||
+		history[0].Credits.Cmp(big.NewRat(14450, 1)) != 0 || history[1].Credits.Cmp(big.NewRat(15000, 1)) != 0 {
+		t.Errorf("Unexpected history %v", history)
+	}
+
+	tests := []struct {
+		at       time.Time
+		expected *big.Rat
+		err      error
+	}{
+		{january, big.NewRat(14450, 1), nil},
+		{march.Add(-time.Second), big.NewRat(14450, 1), nil},
+		{march, big.NewRat(15000, 1), nil},
+		{march.AddDate(1, 0, 0), big.NewRat(15000, 1), nil},
+		{january.Add(-time.Second), nil, constant.ErrNotDefined},
+	}
+
+	for _, test := range tests {
+		credits, err := db.GetCreditsFromCurrencyAt("gold", test.at)
+		if !errors.Is(err, test.err) {
+			t.Errorf("At %v, expected error %v, got %v", test.at, test.err, err)
+		}
+		if test.expected != nil && (credits == nil || credits.Cmp(test.expected) != 0) {
+			t.Errorf("At %v, expected %v, got %v", test.at, test.expected, credits)
+		}
+	}
+
+	var noRate *constant.NoRateError
+	if _, err := db.GetCreditsFromCurrencyAt("gold", january.AddDate(-1, 0, 0)); !errors.As(err, &noRate) {
+		t.Errorf("Expected NoRateError, got %v", err)
+	}
+	if _, err := db.GetCreditsFromCurrencyAt("silver", march); !errors.Is(err, constant.ErrNotDefined) {
+		t.Errorf("Expected ErrNotDefined for silver, got %v", err)
+	}
+
+	// Changing a returned point does not change the history
+	history[0].Credits.SetInt64(1)
+	if credits, _ := db.GetCreditsFromCurrencyAt("gold", january); credits.Cmp(big.NewRat(14450, 1)) != 0 {
+		t.Errorf("Expected the history to be copied, got %v", credits)
+	}
+
+	db.RemoveCurrency("gold")
+	if _, err := db.GetRateHistory("gold"); !errors.Is(err, constant.ErrNotDefined) {
+		t.Errorf("Expected the history to be forgotten, got %v", err)
+	}
+}
+
+func TestFileDatabasePersistsHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	january := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: january}
+
+	db, err := NewFileDatabase(path, WithClock(clock), WithPolicy(PolicyOverwrite))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	db.DefineUnit("glob", "I", sumRate)
+	db.DefineRate("iron", RateSource{Units: []string{"glob"}, Credits: big.NewRat(391, 2)}, sumRate)
+	clock.now = january.AddDate(0, 1, 0)
+	db.DefineRate("iron", RateSource{Units: []string{"glob"}, Credits: big.NewRat(200, 1)}, sumRate)
+
+	reopened, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	history, err := reopened.GetRateHistory("iron")
+	if err != nil || len(history) != 2 || !history[0].Time.Equal(january) || history[0].Credits.Cmp(big.NewRat(391, 2)) != 0 {
+		t.Errorf("Unexpected history %v (%v)", history, err)
+	}
+}
+
+func TestFileDatabaseWithoutHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	content := `{"units": {}, "currencies": {"silver": "17"}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	db, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A rate saved before history was kept holds at any time
+	credits, err := db.GetCreditsFromCurrencyAt("silver", time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || credits.Cmp(big.NewRat(17, 1)) != 0 {
+		t.Errorf("Expected 17, got %v (%v)", credits, err)
+	}
+}
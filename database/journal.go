@@ -0,0 +1,432 @@
+package database
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+// Scope names the units and currencies a snapshot copies. The currencies
+// whose rate is computed from one of the units are copied along with them.
+type Scope struct {
+	Units      []string
+	Currencies []string
+}
+
+// Snapshot is a copy of some units and currencies of a database, restoring it
+// takes back their changes and leaves everything else as it is. A name the
+// snapshot holds no value for was not defined.
+type Snapshot struct {
+	units      map[string]string
+	currencies map[string]*big.Rat
+	sources    map[string]RateSource
+	history    map[string][]RatePoint
+	// unitNames and currencyNames are the names copied, whole is set instead
+	// when the snapshot holds the whole database
+	unitNames     map[string]bool
+	currencyNames map[string]bool
+	whole         bool
+}
+
+func newSnapshot() Snapshot {
+	return Snapshot{
+		units:         make(map[string]string),
+		currencies:    make(map[string]*big.Rat),
+		sources:       make(map[string]RateSource),
+		history:       make(map[string][]RatePoint),
+		unitNames:     make(map[string]bool),
+		currencyNames: make(map[string]bool),
+	}
+}
+
+// Snapshot copies the current content of the scope, later changes do not
+// affect it
+func (db *database) Snapshot(scope Scope) Snapshot {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	snapshot := newSnapshot()
+	for _, unit := range scope.Units {
+		unit = strings.ToLower(unit)
+		db.copyUnit(&snapshot, unit)
+		for _, currency := range db.currenciesUsingUnit(unit) {
+			db.copyCurrency(&snapshot, currency)
+		}
+	}
+	for _, currency := range scope.Currencies {
+		db.copyCurrency(&snapshot, strings.ToLower(currency))
+	}
+	return snapshot
+}
+
+// snapshot copies the whole content, to take back a change that failed
+func (db *database) snapshot() Snapshot {
+	snapshot := newSnapshot()
+	snapshot.whole = true
+	for unit := range db.unitToRomanValues {
+		db.copyUnit(&snapshot, unit)
+	}
+	for currency := range db.currencyToCreditValues {
+		db.copyCurrency(&snapshot, currency)
+	}
+	return snapshot
+}
+
+func (db *database) copyUnit(snapshot *Snapshot, unit string) {
+	snapshot.unitNames[unit] = true
+	if roman, exists := db.unitToRomanValues[unit]; exists {
+		snapshot.units[unit] = roman
+	}
+}
+
+func (db *database) copyCurrency(snapshot *Snapshot, currency string) {
+	snapshot.currencyNames[currency] = true
+	if credits, exists := db.currencyToCreditValues[currency]; exists {
+		snapshot.currencies[currency] = new(big.Rat).Set(credits)
+	}
+	if source, exists := db.currencyToSources[currency]; exists {
+		snapshot.sources[currency] = copySource(source)
+	}
+	if history, exists := db.currencyToHistory[currency]; exists {
+		snapshot.history[currency] = copyHistory(history)
+	}
+}
+
+// Restore puts back the content of a snapshot, ignoring the policy
+func (db *database) Restore(snapshot Snapshot) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.restore(snapshot)
+	return nil
+}
+
+func (db *database) restore(snapshot Snapshot) {
+	if snapshot.whole {
+		db.unitToRomanValues = make(map[string]string, len(snapshot.units))
+		db.currencyToCreditValues = make(map[string]*big.Rat, len(snapshot.currencies))
+		db.currencyToSources = make(map[string]RateSource, len(snapshot.sources))
+		db.currencyToHistory = make(map[string][]RatePoint, len(snapshot.history))
+	}
+
+	for unit := range snapshot.unitNames {
+		delete(db.unitToRomanValues, unit)
+		if roman, exists := snapshot.units[unit]; exists {
+			db.unitToRomanValues[unit] = roman
+		}
+	}
+	for currency := range snapshot.currencyNames {
+		delete(db.currencyToCreditValues, currency)
+		delete(db.currencyToSources, currency)
+		delete(db.currencyToHistory, currency)
+		if credits, exists := snapshot.currencies[currency]; exists {
+			db.currencyToCreditValues[currency] = new(big.Rat).Set(credits)
+		}
+		if source, exists := snapshot.sources[currency]; exists {
+			db.currencyToSources[currency] = copySource(source)
+		}
+		if history, exists := snapshot.history[currency]; exists {
+			db.currencyToHistory[currency] = copyHistory(history)
+		}
+	}
+}
+
+// scope names everything the snapshot copied
+func (s Snapshot) scope() Scope {
+	scope := Scope{}
+	for unit := range s.unitNames {
+		scope.Units = append(scope.Units, unit)
+	}
+	for currency := range s.currencyNames {
+		scope.Currencies = append(scope.Currencies, currency)
+	}
+	return scope
+}
+
+// merge adds the names of other the snapshot does not hold yet, the values
+// copied first are kept
+func (s *Snapshot) merge(other Snapshot) {
+	for unit := range other.unitNames {
+		if s.unitNames[unit] {
+			continue
+		}
+		s.unitNames[unit] = true
+		if roman, exists := other.units[unit]; exists {
+			s.units[unit] = roman
+		}
+	}
+	for currency := range other.currencyNames {
+		if s.currencyNames[currency] {
+			continue
+		}
+		s.currencyNames[currency] = true
+		if credits, exists := other.currencies[currency]; exists {
+			s.currencies[currency] = credits
+		}
+		if source, exists := other.sources[currency]; exists {
+			s.sources[currency] = source
+		}
+		if history, exists := other.history[currency]; exists {
+			s.history[currency] = history
+		}
+	}
+}
+
+// equal reports whether other holds the same content for every name of s
+func (s Snapshot) equal(other Snapshot) bool {
+	for unit := range s.unitNames {
+		roman, exists := s.units[unit]
+		otherRoman, otherExists := other.units[unit]
+		if exists != otherExists || roman != otherRoman {
+			return false
+		}
+	}
+	for currency := range s.currencyNames {
+		credits, exists := s.currencies[currency]
+		otherCredits, otherExists := other.currencies[currency]
+		if exists != otherExists || exists && credits.Cmp(otherCredits) != 0 {
+			return false
+		}
+		source, exists := s.sources[currency]
+		otherSource, otherExists := other.sources[currency]
+		if exists != otherExists || exists && !equalSources(source, otherSource) {
+			return false
+		}
+		if !equalHistories(s.history[currency], other.history[currency]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalSources(a, b RateSource) bool {
+	if a.Stale != b.Stale || a.Credits.Cmp(b.Credits) != 0 || len(a.Units) != len(b.Units) {
+		return false
+	}
+	for i := range a.Units {
+		if a.Units[i] != b.Units[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalHistories(a, b []RatePoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Time.Equal(b[i].Time) || a[i].Credits.Cmp(b[i].Credits) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func copyHistory(history []RatePoint) []RatePoint {
+	points := make([]RatePoint, len(history))
+	for i, point := range history {
+		points[i] = RatePoint{Time: point.Time, Credits: new(big.Rat).Set(point.Credits)}
+	}
+	return points
+}
+
+// holder is a database writing its changes out on its own, such as a file. A
+// journal holds the writes of a step back so they are written once it ends.
+type holder interface {
+	hold()
+	release() error
+}
+
+// Journal keeps the changes made to a database as steps that can be undone
+// and redone. A step is every change of one statement, or of a whole block
+// between Begin and Commit. Only the units and currencies a step changes are
+// copied before it, with the rates computed from those units, and only they
+// are taken back. So queries cost nothing, and the changes other writers of a
+// shared database make meanwhile to other names are kept. A step that leaves
+// its names as they were is not kept. A Journal belongs to a single session
+// and is not safe for concurrent use.
+type Journal struct {
+	Database
+
+	// before is the state of every name the current step changed, before
+	// its first change
+	before  *Snapshot
+	inBlock bool
+	// held is set while the writes of the current step are held back
+	held bool
+	undo []Snapshot
+	redo []Snapshot
+}
+
+// NewJournal records the changes made through it to db
+func NewJournal(db Database) *Journal {
+	return &Journal{Database: db}
+}
+
+func (j *Journal) DefineUnit(unit, roman string, rate RateFunc) ([]string, error) {
+	j.track(Scope{Units: []string{unit}})
+	return j.Database.DefineUnit(unit, roman, rate)
+}
+
+func (j *Journal) ForgetUnit(unit string, rate RateFunc) ([]string, error) {
+	j.track(Scope{Units: []string{unit}})
+	return j.Database.ForgetUnit(unit, rate)
+}
+
+func (j *Journal) DefineRate(currency string, source RateSource, rate RateFunc) (*big.Rat, error) {
+	j.track(Scope{Currencies: []string{currency}})
+	return j.Database.DefineRate(currency, source, rate)
+}
+
+func (j *Journal) RemoveCurrency(currency string) error {
+	j.track(Scope{Currencies: []string{currency}})
+	return j.Database.RemoveCurrency(currency)
+}
+
+// track copies the names a change is about to change, unless the step copied
+// them already, and holds the writes of the step back until it ends
+func (j *Journal) track(scope Scope) {
+	if j.before == nil {
+		if holder, ok := j.Database.(holder); ok {
+			holder.hold()
+			j.held = true
+		}
+		before := newSnapshot()
+		j.before = &before
+	}
+	j.before.merge(j.Database.Snapshot(scope))
+}
+
+// InTransaction reports whether a block was begun and not ended yet
+func (j *Journal) InTransaction() bool {
+	return j.inBlock
+}
+
+// Begin starts a block, its changes are kept or taken back together
+func (j *Journal) Begin() error {
+	if j.inBlock {
+		return constant.ErrInTransaction
+	}
+
+	// Changes not ended yet are a step of their own
+	if err := j.keep(); err != nil {
+		return err
+	}
+	j.inBlock = true
+	j.track(Scope{})
+	return nil
+}
+
+// Commit keeps the changes of the block as a single step
+func (j *Journal) Commit() error {
+	if !j.inBlock {
+		return constant.ErrNoTransaction
+	}
+
+	j.inBlock = false
+	return j.keep()
+}
+
+// Rollback takes back every change of the block
+func (j *Journal) Rollback() error {
+	if !j.inBlock {
+		return constant.ErrNoTransaction
+	}
+
+	j.inBlock = false
+	return j.revert()
+}
+
+// EndStatement ends the step of a statement outside a block, its changes are
+// kept, or taken back when it failed part way. Inside a block it does nothing,
+// the block ends with Commit or Rollback.
+func (j *Journal) EndStatement(failed bool) error {
+	if j.inBlock {
+		return nil
+	}
+	if failed {
+		return j.revert()
+	}
+
+	return j.keep()
+}
+
+// Undo takes back the last step kept
+func (j *Journal) Undo() error {
+	if j.inBlock {
+		return constant.ErrInTransaction
+	}
+	if len(j.undo) == 0 {
+		return constant.ErrNothingToUndo
+	}
+
+	return j.move(&j.undo, &j.redo)
+}
+
+// Redo applies again the last step undone, as long as nothing changed since
+func (j *Journal) Redo() error {
+	if j.inBlock {
+		return constant.ErrInTransaction
+	}
+	if len(j.redo) == 0 {
+		return constant.ErrNothingToRedo
+	}
+
+	return j.move(&j.redo, &j.undo)
+}
+
+// move restores the last snapshot of from, keeping the current state of its
+// names in to
+func (j *Journal) move(from, to *[]Snapshot) error {
+	last := (*from)[len(*from)-1]
+	current := j.Database.Snapshot(last.scope())
+	if err := j.Database.Restore(last); err != nil {
+		return err
+	}
+
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, current)
+	return nil
+}
+
+// keep ends the current step, writing its changes out. A step that changed
+// nothing in the end, e.g. a unit defined again with its symbol, is dropped.
+func (j *Journal) keep() error {
+	if j.before == nil {
+		return nil
+	}
+
+	if !j.before.equal(j.Database.Snapshot(j.before.scope())) {
+		j.undo = append(j.undo, *j.before)
+		// A new change makes the undone steps meaningless
+		j.redo = nil
+	}
+	j.before = nil
+	return j.release()
+}
+
+// revert takes back the current step, the restored state is what gets written
+func (j *Journal) revert() error {
+	if j.before == nil {
+		return nil
+	}
+
+	before := *j.before
+	j.before = nil
+	err := j.Database.Restore(before)
+	if releaseErr := j.release(); err == nil {
+		err = releaseErr
+	}
+	return err
+}
+
+// release writes out the changes held back during the step
+func (j *Journal) release() error {
+	if !j.held {
+		return nil
+	}
+
+	j.held = false
+	return j.Database.(holder).release()
+}
@@ -0,0 +1,248 @@
+package database
+
+import (
+	"errors"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+func TestJournalUndoAndRedo(t *testing.T) {
+	journal := NewJournal(NewDatabase())
+
+	journal.DefineUnit("glob", "I", sumRate)
+	journal.EndStatement(false)
+	journal.DefineUnit("prok", "V", sumRate)
+	journal.DefineRate("silver", RateSource{Units: []string{"glob"}, Credits: big.NewRat(17, 1)}, sumRate)
+	journal.EndStatement(false)
+
+	if err := journal.Undo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Both changes of the second statement are taken back together
+	if _, err := journal.GetRomanFromUnit("prok"); err == nil {
+		t.Error("Expected prok to be undone")
+	}
+	if _, err := journal.GetCreditsFromCurrency("silver"); err == nil {
+		t.Error("Expected silver to be undone")
+	}
+	if _, err := journal.GetRomanFromUnit("glob"); err != nil {
+		t.Errorf("Expected glob to be kept, got %v", err)
+	}
+
+	if err := journal.Redo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if roman, err := journal.GetRomanFromUnit("prok"); err != nil || roman != "V" {
+		t.Errorf("Expected prok to be redone, got %s (%v)", roman, err)
+	}
+	if err := journal.Redo(); !errors.Is(err, constant.ErrNothingToRedo) {
+		t.Errorf("Expected ErrNothingToRedo, got %v", err)
+	}
+
+	// A new change forgets what was undone
+	journal.Undo()
+	journal.DefineUnit("pish", "X", sumRate)
+	journal.EndStatement(false)
+	if err := journal.Redo(); !errors.Is(err, constant.ErrNothingToRedo) {
+		t.Errorf("Expected ErrNothingToRedo after a change, got %v", err)
+	}
+
+	journal.Undo()
+	journal.Undo()
+	if err := journal.Undo(); !errors.Is(err, constant.ErrNothingToUndo) {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+	if units := journal.ListUnits(); len(units) != 0 {
+		t.Errorf("Expected no unit, got %v", units)
+	}
+}
+
+func TestJournalQueriesAreNotSteps(t *testing.T) {
+	journal := NewJournal(NewDatabase())
+
+	journal.DefineUnit("glob", "I", sumRate)
+	journal.EndStatement(false)
+	journal.GetRomanFromUnit("glob")
+	journal.EndStatement(false)
+
+	journal.Undo()
+	if err := journal.Undo(); !errors.Is(err, constant.ErrNothingToUndo) {
+		t.Errorf("Expected a single step, got %v", err)
+	}
+}
+
+func TestJournalFailedStatement(t *testing.T) {
+	journal := NewJournal(NewDatabase())
+	journal.DefineUnit("glob", "I", sumRate)
+	journal.EndStatement(false)
+
+	// The rate was set, then the statement failed
+	journal.DefineRate("silver", RateSource{Units: []string{"glob"}, Credits: big.NewRat(17, 1)}, sumRate)
+	journal.EndStatement(true)
+
+	if _, err := journal.GetCreditsFromCurrency("silver"); err == nil {
+		t.Error("Expected the failed statement to be taken back")
+	}
+	journal.Undo()
+	if err := journal.Undo(); !errors.Is(err, constant.ErrNothingToUndo) {
+		t.Errorf("Expected the failed statement not to be a step, got %v", err)
+	}
+}
+
+func TestJournalUndoKeepsOtherWriters(t *testing.T) {
+	db := NewDatabase()
+	journal := NewJournal(db)
+
+	journal.DefineUnit("glob", "I", sumRate)
+	journal.EndStatement(false)
+	// Another writer of the same database changes other names meanwhile
+	db.DefineUnit("prok", "V", sumRate)
+	db.DefineRate("iron", RateSource{Units: []string{"prok"}, Credits: big.NewRat(50, 1)}, sumRate)
+
+	if err := journal.Undo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := db.GetRomanFromUnit("glob"); err == nil {
+		t.Error("Expected glob to be undone")
+	}
+	if roman, err := db.GetRomanFromUnit("prok"); err != nil || roman != "V" {
+		t.Errorf("Expected prok of the other writer to be kept, got %s (%v)", roman, err)
+	}
+	if credits, err := db.GetCreditsFromCurrency("iron"); err != nil || credits.Cmp(big.NewRat(10, 1)) != 0 {
+		t.Errorf("Expected iron of the other writer to be kept, got %v (%v)", credits, err)
+	}
+
+	if err := journal.Redo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if units := db.ListUnits(); !reflect.DeepEqual(units, []string{"glob", "prok"}) {
+		t.Errorf("Expected [glob prok], got %v", units)
+	}
+}
+
+func TestJournalUndoesRatesOfUnit(t *testing.T) {
+	journal := NewJournal(NewDatabase(WithPolicy(PolicyOverwrite)))
+	journal.DefineUnit("glob", "I", sumRate)
+	journal.EndStatement(false)
+	journal.DefineRate("silver", RateSource{Units: []string{"glob", "glob"}, Credits: big.NewRat(34, 1)}, sumRate)
+	journal.EndStatement(false)
+
+	// The rate following glob is taken back with it
+	journal.DefineUnit("glob", "V", sumRate)
+	journal.EndStatement(false)
+	journal.Undo()
+	if credits, err := journal.GetCreditsFromCurrency("silver"); err != nil || credits.Cmp(big.NewRat(17, 1)) != 0 {
+		t.Errorf("Expected 17, got %v (%v)", credits, err)
+	}
+	if history, _ := journal.GetRateHistory("silver"); len(history) != 1 {
+		t.Errorf("Expected the history of silver to be undone, got %v", history)
+	}
+}
+
+func TestJournalSkipsEmptySteps(t *testing.T) {
+	journal := NewJournal(NewDatabase())
+	journal.DefineUnit("glob", "I", sumRate)
+	journal.EndStatement(false)
+
+	// Defining a unit again with its symbol changes nothing to undo
+	journal.DefineUnit("glob", "I", sumRate)
+	journal.EndStatement(false)
+	journal.Begin()
+	journal.Commit()
+
+	if err := journal.Undo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := journal.GetRomanFromUnit("glob"); err == nil {
+		t.Error("Expected the first undo to take back glob")
+	}
+	if err := journal.Undo(); !errors.Is(err, constant.ErrNothingToUndo) {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+}
+
+func TestJournalBlocks(t *testing.T) {
+	journal := NewJournal(NewDatabase())
+
+	if err := journal.Commit(); !errors.Is(err, constant.ErrNoTransaction) {
+		t.Errorf("Expected ErrNoTransaction, got %v", err)
+	}
+	if err := journal.Rollback(); !errors.Is(err, constant.ErrNoTransaction) {
+		t.Errorf("Expected ErrNoTransaction, got %v", err)
+	}
+
+	journal.Begin()
+	if err := journal.Begin(); !errors.Is(err, constant.ErrInTransaction) {
+		t.Errorf("Expected ErrInTransaction, got %v", err)
+	}
+	if err := journal.Undo(); !errors.Is(err, constant.ErrInTransaction) {
+		t.Errorf("Expected no undo inside a block, got %v", err)
+	}
+	journal.DefineUnit("glob", "I", sumRate)
+	journal.EndStatement(false)
+	journal.DefineUnit("prok", "V", sumRate)
+	// A failed statement inside a block is left to the block
+	journal.EndStatement(true)
+	if !journal.InTransaction() {
+		t.Fatal("Expected the block to be in progress")
+	}
+	if err := journal.Rollback(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if units := journal.ListUnits(); len(units) != 0 {
+		t.Errorf("Expected the block to be rolled back, got %v", units)
+	}
+
+	journal.Begin()
+	journal.DefineUnit("glob", "I", sumRate)
+	journal.EndStatement(false)
+	journal.DefineUnit("prok", "V", sumRate)
+	journal.EndStatement(false)
+	if err := journal.Commit(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if units := journal.ListUnits(); len(units) != 2 {
+		t.Errorf("Expected the block to be kept, got %v", units)
+	}
+
+	// The whole block is a single step
+	journal.Undo()
+	if units := journal.ListUnits(); len(units) != 0 {
+		t.Errorf("Expected the block to be undone, got %v", units)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	db.DefineUnit("glob", "I", sumRate)
+	db.DefineRate("silver", RateSource{Units: []string{"glob"}, Credits: big.NewRat(17, 1)}, sumRate)
+	snapshot := db.Snapshot(Scope{Currencies: []string{"silver"}})
+
+	db.RemoveCurrency("silver")
+	if err := db.Restore(snapshot); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The restored content is saved as well
+	reopened, err := NewFileDatabase(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if credits, err := reopened.GetCreditsFromCurrency("silver"); err != nil || credits.Cmp(big.NewRat(17, 1)) != 0 {
+		t.Errorf("Expected 17, got %v (%v)", credits, err)
+	}
+	if _, err := reopened.GetRateSource("silver"); err != nil {
+		t.Errorf("Expected the source to be restored, got %v", err)
+	}
+	if history, err := reopened.GetRateHistory("silver"); err != nil || len(history) != 1 {
+		t.Errorf("Expected the history to be restored, got %v (%v)", history, err)
+	}
+}
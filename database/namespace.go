@@ -0,0 +1,96 @@
+package database
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+// DefaultNamespace is used when no namespace is chosen
+const DefaultNamespace = "default"
+
+// namespacePattern is every name a namespace can have, short enough to be
+// part of a file name
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Namespaces keeps an isolated database per name, each with its own units and
+// currencies. A database is opened the first time its name is asked for. It is
+// safe for concurrent use.
+type Namespaces struct {
+	mu        sync.Mutex
+	open      func(name string) (Database, error)
+	exists    func(name string) bool
+	databases map[string]Database
+}
+
+// NewNamespaces opens the database of each namespace with open. exists tells
+// whether a namespace not opened yet was created before, e.g. in an earlier
+// run, nil when only the namespaces opened exist.
+func NewNamespaces(open func(name string) (Database, error), exists func(name string) bool) *Namespaces {
+	return &Namespaces{open: open, exists: exists, databases: make(map[string]Database)}
+}
+
+// Get returns the database of a namespace, opening it on first use and
+// creating it when it does not exist yet. Names are up to 32 letters, digits,
+// - and _ and are matched ignoring case.
+func (n *Namespaces) Get(name string) (Database, error) {
+	return n.get(name, true)
+}
+
+// Find is Get for a namespace that exists already, it never creates one
+func (n *Namespaces) Find(name string) (Database, error) {
+	return n.get(name, false)
+}
+
+func (n *Namespaces) get(name string, create bool) (Database, error) {
+	name = strings.ToLower(name)
+	if !namespacePattern.MatchString(name) {
+		return nil, &constant.InvalidNamespaceError{Namespace: name}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if db, exists := n.databases[name]; exists {
+		return db, nil
+	}
+	if !create && (n.exists == nil || !n.exists(name)) {
+		return nil, &constant.UnknownNamespaceError{Namespace: name}
+	}
+
+	db, err := n.open(name)
+	if err != nil {
+		return nil, err
+	}
+	n.databases[name] = db
+	return db, nil
+}
+
+// List returns every namespace opened so far, sorted
+func (n *Namespaces) List() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	names := make([]string, 0, len(n.databases))
+	for name := range n.databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NamespacePath is the file of a namespace next to path, the default
+// namespace is kept in path itself, e.g. db.json and db.team-a.json
+func NamespacePath(path, namespace string) string {
+	if namespace == DefaultNamespace {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + namespace + ext
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+func TestNamespaces(t *testing.T) {
+	opened := make([]string, 0)
+	namespaces := NewNamespaces(func(name string) (Database, error) {
+		opened = append(opened, name)
+		return NewDatabase(), nil
+	}, nil)
+
+	teamA, err := namespaces.Get("Team-A")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	teamB, _ := namespaces.Get("team_b")
+	teamA.DefineUnit("glob", "I", sumRate)
+	teamB.DefineUnit("glob", "X", sumRate)
+
+	// Each namespace keeps its own units
+	again, _ := namespaces.Get("team-a")
+	if roman, _ := again.GetRomanFromUnit("glob"); roman != "I" {
+		t.Errorf("Expected I in team-a, got %s", roman)
+	}
+	if roman, _ := teamB.GetRomanFromUnit("glob"); roman != "X" {
+		t.Errorf("Expected X in team_b, got %s", roman)
+	}
+
+	if !reflect.DeepEqual(opened, []string{"team-a", "team_b"}) {
+		t.Errorf("Expected every namespace to be opened once, got %v", opened)
+	}
+	if names := namespaces.List(); !reflect.DeepEqual(names, []string{"team-a", "team_b"}) {
+		t.Errorf("Expected [team-a team_b], got %v", names)
+	}
+
+	for _, name := range []string{"", "../etc", "team a", "-a", strings.Repeat("a", 33)} {
+		var invalid *constant.InvalidNamespaceError
+		if _, err := namespaces.Get(name); !errors.As(err, &invalid) {
+			t.Errorf("Expected InvalidNamespaceError for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestNamespacesOpenFailure(t *testing.T) {
+	failure := errors.New("disk is full")
+	namespaces := NewNamespaces(func(name string) (Database, error) {
+		return nil, failure
+	}, nil)
+
+	if _, err := namespaces.Get("team-a"); !errors.Is(err, failure) {
+		t.Errorf("Expected the open error, got %v", err)
+	}
+	if names := namespaces.List(); len(names) != 0 {
+		t.Errorf("Expected no namespace, got %v", names)
+	}
+}
+
+func TestNamespacesFind(t *testing.T) {
+	namespaces := NewNamespaces(func(name string) (Database, error) {
+		return NewDatabase(), nil
+	}, func(name string) bool {
+		return name == "saved"
+	})
+
+	tests := []struct {
+		name     string
+		hasError bool
+	}{
+		{"saved", false},
+		{"Saved", false},
+		{"team-a", true},
+		{"team a", true},
+	}
+
+	for _, test := range tests {
+		_, err := namespaces.Find(test.name)
+		if test.hasError != (err != nil) {
+			t.Errorf("Find(%q) error = %v, want error %v", test.name, err, test.hasError)
+		}
+	}
+
+	var unknown *constant.UnknownNamespaceError
+	if _, err := namespaces.Find("team-a"); !errors.As(err, &unknown) {
+		t.Errorf("Expected UnknownNamespaceError, got %v", err)
+	}
+
+	// A namespace created with Get is found afterwards
+	if _, err := namespaces.Get("team-a"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := namespaces.Find("team-a"); err != nil {
+		t.Errorf("Expected team-a to be found, got %v", err)
+	}
+	if names := namespaces.List(); !reflect.DeepEqual(names, []string{"saved", "team-a"}) {
+		t.Errorf("Expected [saved team-a], got %v", names)
+	}
+}
+
+func TestNamespacePath(t *testing.T) {
+	tests := []struct {
+		path      string
+		namespace string
+		expected  string
+	}{
+		{"db.json", DefaultNamespace, "db.json"},
+		{"db.json", "team-a", "db.team-a.json"},
+		{filepath.Join("data", "intergalactic"), "team-a", filepath.Join("data", "intergalactic.team-a")},
+	}
+
+	for _, test := range tests {
+		if got := NamespacePath(test.path, test.namespace); got != test.expected {
+			t.Errorf("NamespacePath(%q, %q) = %q, want %q", test.path, test.namespace, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+// Reading is what Read found of units and currencies, by lower case name.
+// Names not defined are left out.
+type Reading struct {
+	Symbols map[string]string
+	Rates   map[string]*big.Rat
+	Sources map[string]RateSource
+}
+
+// reading answers for the units and currencies it was read for with what the
+// database held at that moment, and asks the database for anything else
+type reading struct {
+	Database
+	Reading
+	units      map[string]bool
+	currencies map[string]bool
+}
+
+// ReadAtOnce returns a database answering for the units and the currencies,
+// the sources of their rates included, from a single Read of db, so nothing it
+// answers about them mixes two states of db. A database already read at once
+// is returned as it is.
+func ReadAtOnce(db Database, units, currencies []string) Database {
+	if _, ok := db.(*reading); ok {
+		return db
+	}
+
+	r := &reading{
+		Database:   db,
+		Reading:    db.Read(units, currencies),
+		units:      make(map[string]bool, len(units)),
+		currencies: make(map[string]bool, len(currencies)),
+	}
+	for _, unit := range units {
+		r.units[strings.ToLower(unit)] = true
+	}
+	for _, currency := range currencies {
+		r.currencies[strings.ToLower(currency)] = true
+	}
+	for _, source := range r.Sources {
+		for _, unit := range source.Units {
+			r.units[strings.ToLower(unit)] = true
+		}
+	}
+	return r
+}
+
+func (r *reading) GetRomanFromUnit(unit string) (string, error) {
+	if !r.units[strings.ToLower(unit)] {
+		return r.Database.GetRomanFromUnit(unit)
+	}
+	if roman, exists := r.Symbols[strings.ToLower(unit)]; exists {
+		return roman, nil
+	}
+	return "", &constant.UnknownUnitError{Unit: unit}
+}
+
+func (r *reading) GetCreditsFromCurrency(currency string) (*big.Rat, error) {
+	if !r.currencies[strings.ToLower(currency)] {
+		return r.Database.GetCreditsFromCurrency(currency)
+	}
+	if credits, exists := r.Rates[strings.ToLower(currency)]; exists {
+		return new(big.Rat).Set(credits), nil
+	}
+	return nil, &constant.UnknownCurrencyError{Currency: currency}
+}
+
+func (r *reading) GetCreditsFromCurrencies(currencies ...string) ([]*big.Rat, error) {
+	rates := make([]*big.Rat, len(currencies))
+	for i, currency := range currencies {
+		credits, err := r.GetCreditsFromCurrency(currency)
+		if err != nil {
+			return nil, err
+		}
+		rates[i] = credits
+	}
+	return rates, nil
+}
+
+func (r *reading) GetRateSource(currency string) (RateSource, error) {
+	if !r.currencies[strings.ToLower(currency)] {
+		return r.Database.GetRateSource(currency)
+	}
+	if source, exists := r.Sources[strings.ToLower(currency)]; exists {
+		return copySource(source), nil
+	}
+	return RateSource{}, &constant.UnknownCurrencyError{Currency: currency}
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+func TestReadAtOnce(t *testing.T) {
+	db := NewDatabase(WithPolicy(PolicyOverwrite))
+	db.DefineUnit("glob", "I", sumRate)
+	db.DefineUnit("prok", "V", sumRate)
+	db.DefineRate("silver", RateSource{Units: []string{"glob", "glob"}, Credits: big.NewRat(34, 1)}, sumRate)
+
+	view := ReadAtOnce(db, []string{"Prok", "pish"}, []string{"Silver", "gold"})
+
+	// Changes made after the read are not seen for the names read
+	db.DefineUnit("glob", "X", sumRate)
+	db.DefineUnit("prok", "L", sumRate)
+	db.DefineUnit("pish", "C", sumRate)
+	db.DefineRate("gold", RateSource{Units: []string{"prok"}, Credits: big.NewRat(100, 1)}, sumRate)
+
+	if roman, err := view.GetRomanFromUnit("prok"); err != nil || roman != "V" {
+		t.Errorf("Expected V, got %s (%v)", roman, err)
+	}
+	// The units of a rate source are read with it
+	if roman, err := view.GetRomanFromUnit("glob"); err != nil || roman != "I" {
+		t.Errorf("Expected glob of the silver source to be I, got %s (%v)", roman, err)
+	}
+	if credits, err := view.GetCreditsFromCurrency("silver"); err != nil || credits.Cmp(big.NewRat(17, 1)) != 0 {
+		t.Errorf("Expected 17, got %v (%v)", credits, err)
+	}
+	if source, err := view.GetRateSource("silver"); err != nil || !reflect.DeepEqual(source.Units, []string{"glob", "glob"}) {
+		t.Errorf("Expected the source of silver, got %v (%v)", source, err)
+	}
+
+	// Names not defined when read stay unknown
+	if _, err := view.GetRomanFromUnit("pish"); !errors.Is(err, constant.ErrNotDefined) {
+		t.Errorf("Expected pish to be unknown, got %v", err)
+	}
+	if _, err := view.GetCreditsFromCurrencies("silver", "gold"); !errors.Is(err, constant.ErrNotDefined) {
+		t.Errorf("Expected gold to be unknown, got %v", err)
+	}
+
+	// Other names are asked to the database
+	db.DefineUnit("tegj", "L", sumRate)
+	if roman, err := view.GetRomanFromUnit("tegj"); err != nil || roman != "L" {
+		t.Errorf("Expected L, got %s (%v)", roman, err)
+	}
+	if again := ReadAtOnce(view, []string{"tegj"}, nil); again != view {
+		t.Error("Expected a database read at once to be returned as it is")
+	}
+}
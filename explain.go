@@ -0,0 +1,385 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/erizkiatama/prospace-assignment/calculator"
+	"github.com/erizkiatama/prospace-assignment/database"
+	"github.com/erizkiatama/prospace-assignment/parser"
+)
+
+// explainKeyword prefixes a statement whose answer is explained
+const explainKeyword = "explain"
+
+// explainedLine strips a leading explain keyword from a line, blanking it so
+// the columns of the rest stay where they were written
+func explainedLine(line string) (string, bool) {
+	tokens := parser.Lex(line)
+	if len(tokens) < 3 || !strings.EqualFold(tokens[0].Text, explainKeyword) {
+		return "", false
+	}
+
+	runes := []rune(line)
+	for i := tokens[0].Pos - 1; i < tokens[0].Pos-1+len([]rune(tokens[0].Text)); i++ {
+		runes[i] = ' '
+	}
+	return string(runes), true
+}
+
+// basis is what an answer was computed from. Its explanation reads the same
+// database and shows the figures of the answer, rather than computing them
+// again from a database that may have changed meanwhile.
+type basis struct {
+	db   database.Database
+	calc calculator.Calculator
+	// bought is the outcome of a purchase query
+	bought *bought
+}
+
+// bought is how many whole units a budget bought, at which rate, and the
+// credits left
+type bought struct {
+	quantity int
+	rate     *big.Rat
+	left     *big.Rat
+}
+
+// explain derives the answer of a statement step by step: the symbols of its
+// units, how they add up, the rates used with where they come from, and the
+// final arithmetic with its rounding. Statements without arithmetic have no
+// steps. The answer is explained from its basis when it has one.
+func explain(db database.Database, calc calculator.Calculator, stmt parser.Statement, based basis) ([]string, error) {
+	if based.db != nil {
+		db, calc = based.db, based.calc
+	}
+	e := &explainer{db: db, calc: calc}
+
+	switch stmt := stmt.(type) {
+	case parser.RateDefinition:
+		value, err := e.units(stmt.Units)
+		if err != nil {
+			return nil, err
+		}
+		rate := new(big.Rat).Quo(stmt.Credits, big.NewRat(int64(value), 1))
+		e.add("1 %s is %s / %d = %s Credits", stmt.Currency, exact(stmt.Credits), value, exact(rate))
+	case parser.NumeralQuery:
+		if _, err := e.amount(stmt.Units, stmt.Expression); err != nil {
+			return nil, err
+		}
+	case parser.CreditQuery:
+		value, err := e.amount(stmt.Units, stmt.Expression)
+		if err != nil {
+			return nil, err
+		}
+		rate, err := e.rate(stmt.Currency, stmt.At)
+		if err != nil {
+			return nil, err
+		}
+		e.credits(big.NewRat(int64(value), 1), rate, true)
+	case parser.ConversionQuery:
+		if err := e.conversion(stmt); err != nil {
+			return nil, err
+		}
+	case parser.PurchaseQuery:
+		if err := e.purchase(stmt, based.bought); err != nil {
+			return nil, err
+		}
+	case parser.TranslationQuery:
+		units, err := calc.ConvertIntToUnits(stmt.Number, stmt.System)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := e.units(units); err != nil {
+			return nil, err
+		}
+	case parser.UnitComparison:
+		first, err := e.units(stmt.First)
+		if err != nil {
+			return nil, err
+		}
+		second, err := e.units(stmt.Second)
+		if err != nil {
+			return nil, err
+		}
+		e.add("%d %s %d", first, comparisonSign(big.NewRat(int64(first), 1).Cmp(big.NewRat(int64(second), 1))), second)
+	case parser.CreditComparison:
+		first, err := e.quantity(stmt.First)
+		if err != nil {
+			return nil, err
+		}
+		second, err := e.quantity(stmt.Second)
+		if err != nil {
+			return nil, err
+		}
+		e.add("%s %s %s Credits", exact(first), comparisonSign(first.Cmp(second)), exact(second))
+	}
+
+	return e.steps, nil
+}
+
+type explainer struct {
+	db    database.Database
+	calc  calculator.Calculator
+	steps []string
+}
+
+func (e *explainer) add(format string, args ...any) {
+	e.steps = append(e.steps, fmt.Sprintf(format, args...))
+}
+
+// units explains how units are read as a number
+func (e *explainer) units(units []string) (int, error) {
+	derivation, err := e.calc.DeriveUnits(units)
+	if err != nil {
+		return 0, err
+	}
+
+	assignments := make([]string, 0, len(units))
+	seen := make(map[string]bool)
+	for i, unit := range units {
+		if !seen[unit] {
+			seen[unit] = true
+			assignments = append(assignments, fmt.Sprintf("%s is %s", unit, derivation.Symbols[i]))
+		}
+	}
+	e.add("%s is %s in %s, as %s",
+		strings.Join(units, " "), numeralText(derivation.Symbols), derivation.System, strings.Join(assignments, ", "),
+	)
+
+	for _, term := range derivation.Terms {
+		e.add("%s", term.Text)
+	}
+	if sum := derivation.Sum(); sum != "" {
+		e.add("%s", sum)
+	}
+	return derivation.Value, nil
+}
+
+// amount explains the value of units, or of an expression when it is set
+func (e *explainer) amount(units []string, expr parser.Expression) (int, error) {
+	if expr == nil {
+		return e.units(units)
+	}
+	return e.expression(expr)
+}
+
+// expression explains every operand of an expression, then every operation
+// in the order it is computed
+func (e *explainer) expression(expr parser.Expression) (int, error) {
+	switch expr := expr.(type) {
+	case parser.Operand:
+		return e.units(expr.Units)
+	case parser.Operation:
+		left, err := e.expression(expr.Left)
+		if err != nil {
+			return 0, err
+		}
+		right, err := e.expression(expr.Right)
+		if err != nil {
+			return 0, err
+		}
+		value, err := calculator.Operate(expr.Operator, left, right)
+		if err != nil {
+			return 0, err
+		}
+		e.add("%d %s %d = %d", left, operatorSign(expr.Operator), right, value)
+		return value, nil
+	}
+	return 0, fmt.Errorf("unsupported expression %T", expr)
+}
+
+// rate explains the credits of a single unit of a currency, now or on a day
+func (e *explainer) rate(currency string, at *time.Time) (*big.Rat, error) {
+	currency = strings.ToLower(currency)
+	if at != nil {
+		return e.pastRate(currency, *at)
+	}
+
+	credits, err := e.db.GetCreditsFromCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	return credits, e.rateOf(currency, credits)
+}
+
+// rateOf explains where the current rate of a currency comes from
+func (e *explainer) rateOf(currency string, credits *big.Rat) error {
+	source, err := e.db.GetRateSource(currency)
+	switch {
+	case err != nil:
+		e.add("1 %s is %s Credits, as defined", currency, exact(credits))
+	case source.Stale:
+		e.add("1 %s is %s Credits, the last rate kept since the units of %q changed",
+			currency, exact(credits), sourceSentence(currency, source),
+		)
+	default:
+		value, err := e.calc.ConvertUnitsToInt(source.Units)
+		if err != nil {
+			return err
+		}
+		e.add("1 %s is %s / %d = %s Credits, from %q",
+			currency, exact(source.Credits), value, exact(credits), sourceSentence(currency, source),
+		)
+	}
+	return nil
+}
+
+// pastRate explains the rate a currency had at the end of a day
+func (e *explainer) pastRate(currency string, at time.Time) (*big.Rat, error) {
+	endOfDay := calculator.EndOfDay(at)
+	credits, err := e.db.GetCreditsFromCurrencyAt(currency, endOfDay)
+	if err != nil {
+		return nil, err
+	}
+	points, err := e.db.GetRateHistory(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	since := "from the start"
+	for _, point := range points {
+		if point.Time.After(endOfDay) {
+			break
+		}
+		if !point.Time.IsZero() {
+			since = "set on " + point.Time.Format(time.RFC3339)
+		}
+	}
+	e.add("1 %s was %s Credits on %s, %s", currency, exact(credits), at.Format(time.DateOnly), since)
+	return credits, nil
+}
+
+// credits explains the multiplication of a quantity by a rate, and its
+// rounding when it is the answer
+func (e *explainer) credits(quantity, rate *big.Rat, answer bool) *big.Rat {
+	credits := new(big.Rat).Mul(quantity, rate)
+	step := fmt.Sprintf("%s × %s = %s Credits", exact(quantity), exact(rate), exact(credits))
+	if answer {
+		step += ", rounded to " + credits.FloatString(2)
+	}
+	e.add("%s", step)
+	return credits
+}
+
+func (e *explainer) quantity(quantity parser.Quantity) (*big.Rat, error) {
+	value, err := e.units(quantity.Units)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := e.rate(quantity.Currency, nil)
+	if err != nil {
+		return nil, err
+	}
+	return e.credits(big.NewRat(int64(value), 1), rate, false), nil
+}
+
+func (e *explainer) conversion(stmt parser.ConversionQuery) error {
+	quantity := stmt.Amount
+	if quantity == nil {
+		value, err := e.units(stmt.Units)
+		if err != nil {
+			return err
+		}
+		quantity = big.NewRat(int64(value), 1)
+	}
+
+	rate, err := e.rate(stmt.Currency, nil)
+	if err != nil {
+		return err
+	}
+	toCredits := strings.EqualFold(stmt.Target, "credits")
+	credits := e.credits(quantity, rate, toCredits)
+	if toCredits {
+		return nil
+	}
+
+	targetRate, err := e.rate(stmt.Target, nil)
+	if err != nil {
+		return err
+	}
+	if targetRate.Sign() == 0 {
+		return nil
+	}
+	result := new(big.Rat).Quo(credits, targetRate)
+	e.add("%s / %s = %s %s, rounded to %s",
+		exact(credits), exact(targetRate), exact(result), strings.ToLower(stmt.Target), formatQuantity(result),
+	)
+	return nil
+}
+
+// purchase explains the division of a budget by a rate, rounded down to
+// whole units, and what is left of it. The purchase answered is explained, it
+// is only computed when there is none.
+func (e *explainer) purchase(stmt parser.PurchaseQuery, purchase *bought) error {
+	if purchase == nil {
+		quantity, left, rate, err := e.calc.CalculatePurchase(stmt.Credits, stmt.Currency)
+		if err != nil {
+			return err
+		}
+		purchase = &bought{quantity: quantity, rate: rate, left: left}
+	}
+	quantity, rate, left := purchase.quantity, purchase.rate, purchase.left
+	if err := e.rateOf(strings.ToLower(stmt.Currency), rate); err != nil {
+		return err
+	}
+
+	e.add("%s / %s = %d whole %s, rounded down", exact(stmt.Credits), exact(rate), quantity, strings.ToLower(stmt.Currency))
+	e.add("%s - %d × %s = %s Credits left, rounded to %s",
+		exact(stmt.Credits), quantity, exact(rate), exact(left), left.FloatString(2),
+	)
+	return nil
+}
+
+func sourceSentence(currency string, source database.RateSource) string {
+	return fmt.Sprintf("%s %s is %s Credits", strings.Join(source.Units, " "), currency, exact(source.Credits))
+}
+
+func comparisonSign(comparison int) string {
+	switch comparison {
+	case 1:
+		return ">"
+	case -1:
+		return "<"
+	}
+	return "="
+}
+
+func operatorSign(operator string) string {
+	switch operator {
+	case parser.Plus:
+		return "+"
+	case parser.Minus:
+		return "-"
+	case parser.Times:
+		return "×"
+	}
+	return "/"
+}
+
+// exact writes a rational without losing anything, as a decimal when it has
+// a short one and as a fraction otherwise
+func exact(value *big.Rat) string {
+	if value.IsInt() {
+		return value.RatString()
+	}
+	decimal := formatQuantity(value)
+	if parsed, ok := new(big.Rat).SetString(decimal); ok && parsed.Cmp(value) == 0 {
+		return decimal
+	}
+	return fmt.Sprintf("%s (%s...)", value.RatString(), decimal)
+}
+
+// numeralText writes symbols as a single numeral, the digits of a positional
+// system being kept apart
+func numeralText(symbols []string) string {
+	for _, symbol := range symbols {
+		if symbol != "" && unicode.IsDigit([]rune(symbol)[0]) {
+			return strings.Join(symbols, " ")
+		}
+	}
+	return strings.Join(symbols, "")
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/calculator"
+	"github.com/erizkiatama/prospace-assignment/database"
+	"github.com/erizkiatama/prospace-assignment/parser"
+)
+
+func TestExplainedLine(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		explained bool
+	}{
+		{"explain how much is glob ?", "        how much is glob ?", true},
+		{"  EXPLAIN how much is glob ?", "          how much is glob ?", true},
+		{"how much is glob ?", "", false},
+		{"explain is I", "        is I", true},
+		{"explainer is I", "", false},
+	}
+
+	for _, tt := range tests {
+		line, explained := explainedLine(tt.input)
+		if line != tt.expected || explained != tt.explained {
+			t.Errorf("explainedLine(%q) = %q, %v, want %q, %v", tt.input, line, explained, tt.expected, tt.explained)
+		}
+	}
+}
+
+func TestRunIntergalacticConverterExplain(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  sessionOptions
+		expected []string
+	}{
+		{
+			name: "Explain prefix",
+			input: `glob is I
+prok is V
+glob glob Silver is 34 Credits
+explain how many Credits is glob prok Silver ?
+how much is prok glob ?
+explain how much is wood ?`,
+			expected: []string{
+				"glob prok silver is 68.00 Credits",
+				"  glob prok is IV in roman, as glob is I, prok is V",
+				"  IV = 5 - 1 = 4",
+				`  1 silver is 34 / 2 = 17 Credits, from "glob glob silver is 34 Credits"`,
+				"  4 × 17 = 68 Credits, rounded to 68.00",
+				"prok glob is 6",
+				"script.txt:6:21: wood unit is not defined in the intergalactic database",
+			},
+		},
+		{
+			name: "Explained expression",
+			input: `glob is I
+prok is V
+pish is X
+glob glob Silver is 34 Credits
+explain how many Credits is (pish minus glob) divided by glob glob glob Silver ?`,
+			expected: []string{
+				"((pish minus glob) divided by glob glob glob) silver is 51.00 Credits",
+				"  pish is X in roman, as pish is X",
+				"  X = 10",
+				"  glob is I in roman, as glob is I",
+				"  I = 1",
+				"  10 - 1 = 9",
+				"  glob glob glob is III in roman, as glob is I",
+				"  III = 1 + 1 + 1 = 3",
+				"  9 / 3 = 3",
+				`  1 silver is 34 / 2 = 17 Credits, from "glob glob silver is 34 Credits"`,
+				"  3 × 17 = 51 Credits, rounded to 51.00",
+			},
+		},
+		{
+			name: "Explained purchase",
+			input: `glob is I
+prok is V
+glob prok Iron is 782 Credits
+explain how many Iron can I buy with 1000 Credits ?`,
+			expected: []string{
+				"1000 Credits buy 5 iron (prok iron), 22.50 Credits left",
+				`  1 iron is 782 / 4 = 195.5 Credits, from "glob prok iron is 782 Credits"`,
+				"  1000 / 195.5 = 5 whole iron, rounded down",
+				"  1000 - 5 × 195.5 = 22.5 Credits left, rounded to 22.50",
+			},
+		},
+		{
+			name: "A unit named explain",
+			input: `explain is I
+explain how much is explain explain ?`,
+			expected: []string{
+				"explain explain is 2",
+				"  explain explain is II in roman, as explain is I",
+				"  II = 1 + 1 = 2",
+			},
+		},
+		{
+			name: "Explain flag",
+			input: `glob is I
+prok is V
+glob glob Silver is 34 Credits
+glob prok Gold is 57800 Credits
+how many Gold is 10 Silver ?`,
+			options: sessionOptions{explain: true},
+			expected: []string{
+				"  glob glob is II in roman, as glob is I",
+				"  II = 1 + 1 = 2",
+				"  1 silver is 34 / 2 = 17 Credits",
+				"  glob prok is IV in roman, as glob is I, prok is V",
+				"  IV = 5 - 1 = 4",
+				"  1 gold is 57800 / 4 = 14450 Credits",
+				"10 silver is 0.011765 gold",
+				`  1 silver is 34 / 2 = 17 Credits, from "glob glob silver is 34 Credits"`,
+				"  10 × 17 = 170 Credits",
+				`  1 gold is 57800 / 4 = 14450 Credits, from "glob prok gold is 57800 Credits"`,
+				"  170 / 14450 = 1/85 (0.011765...) gold, rounded to 0.011765",
+			},
+		},
+		{
+			name: "Explanation in JSON",
+			input: `glob is I
+prok is V
+explain how much is glob prok ?`,
+			options: sessionOptions{output: jsonOutput},
+			expected: []string{
+				`{"source":"script.txt","line":1,"type":"unit_definition","operands":{"unit":"glob","symbol":"i"},"result":{"unit":"glob","symbol":"I"}}`,
+				`{"source":"script.txt","line":2,"type":"unit_definition","operands":{"unit":"prok","symbol":"v"},"result":{"unit":"prok","symbol":"V"}}`,
+				`{"source":"script.txt","line":3,"type":"numeral_query","operands":{"units":["glob","prok"]},"text":"glob prok is 4","result":{"value":4},"roman":["IV"],"explanation":["glob prok is IV in roman, as glob is I, prok is V","IV = 5 - 1 = 4"]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := database.NewDatabase()
+			var output bytes.Buffer
+
+			err := runIntergalacticConverter(
+				calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "script.txt",
+				bytes.NewBufferString(tt.input), &output, tt.options,
+			)
+			if err != nil {
+				t.Fatalf("runIntergalacticConverter() error = %v", err)
+			}
+
+			if got := outputLines(output.String()); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("runIntergalacticConverter() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExplainFromBasis(t *testing.T) {
+	db := database.NewDatabase(database.WithPolicy(database.PolicyOverwrite))
+	calc := calculator.NewCalculator(db)
+	for _, line := range []string{"glob is I", "prok is V", "glob prok Iron is 782 Credits"} {
+		stmt, err := parser.Parse(line)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", line, err)
+		}
+		if _, err := execute(db, calc, stmt); err != nil {
+			t.Fatalf("execute(%q) error = %v", line, err)
+		}
+	}
+
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{
+			line: "how many Iron can I buy with 1000 Credits ?",
+			expected: []string{
+				`1 iron is 782 / 4 = 195.5 Credits, from "glob prok iron is 782 Credits"`,
+				"1000 / 195.5 = 5 whole iron, rounded down",
+				"1000 - 5 × 195.5 = 22.5 Credits left, rounded to 22.50",
+			},
+		},
+		{
+			line: "how many Credits is prok Iron ?",
+			expected: []string{
+				"prok is V in roman, as prok is V",
+				"V = 5",
+				`1 iron is 782 / 4 = 195.5 Credits, from "glob prok iron is 782 Credits"`,
+				"5 × 195.5 = 977.5 Credits, rounded to 977.50",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			stmt, err := parser.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			answer, err := execute(db, calc, stmt)
+			if err != nil {
+				t.Fatalf("execute() error = %v", err)
+			}
+
+			// The answer given is explained, not the database as it is now
+			db.DefineUnit("glob", "X", calc.RateFromSymbols)
+			db.DefineUnit("prok", "L", calc.RateFromSymbols)
+			defer db.DefineUnit("glob", "I", calc.RateFromSymbols)
+			defer db.DefineUnit("prok", "V", calc.RateFromSymbols)
+
+			steps, err := explain(db, calc, stmt, answer.basis)
+			if err != nil {
+				t.Fatalf("explain() error = %v", err)
+			}
+			if !reflect.DeepEqual(steps, tt.expected) {
+				t.Errorf("explain() = %q, want %q", steps, tt.expected)
+			}
+		})
+	}
+}
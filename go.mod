@@ -1,3 +1,10 @@
 module github.com/erizkiatama/prospace-assignment
 
-go 1.22.0
+go 1.23.0
+
+require (
+	golang.org/x/term v0.34.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.35.0 // indirect
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+	"github.com/erizkiatama/prospace-assignment/parser"
+)
+
+// inputFormat is how the statements of a session are written
+type inputFormat int
+
+const (
+	// autoInput reads sentences and JSON documents, a line starting with { is
+	// a document. Input starting with --- or op: is read as YAML documents.
+	autoInput inputFormat = iota
+	// textInput reads a sentence per line
+	textInput
+	// jsonInput reads a JSON document per line
+	jsonInput
+	// yamlInput reads YAML documents separated by --- lines
+	yamlInput
+)
+
+// parseInputFormat reads an input format from its name, auto, text, json or yaml
+func parseInputFormat(name string) (inputFormat, error) {
+	switch strings.ToLower(name) {
+	case "auto":
+		return autoInput, nil
+	case "text":
+		return textInput, nil
+	case "json":
+		return jsonInput, nil
+	case "yaml":
+		return yamlInput, nil
+	}
+	return 0, fmt.Errorf("unknown input format %q, use auto, text, json or yaml", name)
+}
+
+// detectInput settles the auto format on the first statement line, YAML is
+// recognized by its document start or by a leading op field
+func (s *session) detectInput(trimmed string) {
+	if s.input != autoInput || s.detected || isSkippedLine(trimmed) {
+		return
+	}
+	s.detected = true
+	if trimmed == "---" || strings.HasPrefix(trimmed, "op:") {
+		s.input = yamlInput
+	}
+}
+
+// parseLine reads the statement of a line, as a sentence or a JSON document
+func (s *session) parseLine(line string) (parser.Statement, error) {
+	if s.input == jsonInput && !parser.IsDocument(line) {
+		return nil, fmt.Errorf("%w: a JSON document starts with {", constant.ErrInvalidDocument)
+	}
+	if s.input == jsonInput || (s.input == autoInput && parser.IsDocument(line)) {
+		return parser.ParseDocument(strings.TrimSpace(line))
+	}
+	return parser.Parse(strings.ToLower(line))
+}
+
+// readYAML gathers the lines of a YAML document until the --- line starting
+// the next one, or the end of the input
+func (s *session) readYAML(line string) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "---" || trimmed == "..." {
+		s.flushDocument()
+		return
+	}
+	if len(s.document) == 0 {
+		if isSkippedLine(trimmed) {
+			return
+		}
+		s.documentLine = s.line
+	}
+	s.document = append(s.document, line)
+}
+
+// flushDocument answers the YAML document gathered so far, located at its
+// first line or at the line of the field it is wrong about
+func (s *session) flushDocument() {
+	if len(s.document) == 0 {
+		return
+	}
+	lines := s.document
+	s.document = nil
+
+	current := s.line
+	s.line = s.documentLine
+	stmt, err := parser.ParseDocument(strings.Join(lines, "\n"))
+	line := lines[0]
+	var fieldErr *parser.FieldError
+	if errors.As(err, &fieldErr) && fieldErr.Line > 0 {
+		s.line += fieldErr.Line - 1
+		line = lines[fieldErr.Line-1]
+	}
+	s.handleStatement(line, stmt, err, s.explain)
+	s.line = current
+}
+
+// fieldColumn finds the column of the key of a field in a JSON line, 0 when it
+// is not written there
+func fieldColumn(line, field string) int {
+	key := field[strings.LastIndex(field, ".")+1:]
+	if index := strings.Index(key, "["); index >= 0 {
+		key = key[:index]
+	}
+
+	index := strings.Index(line, strconv.Quote(key)+":")
+	if index < 0 {
+		index = strings.Index(line, strconv.Quote(key))
+	}
+	if index < 0 {
+		return 0
+	}
+	return len([]rune(line[:index])) + 1
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/calculator"
+	"github.com/erizkiatama/prospace-assignment/database"
+)
+
+func TestParseInputFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected inputFormat
+		isError  bool
+	}{
+		{"auto", autoInput, false},
+		{"text", textInput, false},
+		{"JSON", jsonInput, false},
+		{"yaml", yamlInput, false},
+		{"xml", 0, true},
+	}
+
+	for _, tt := range tests {
+		format, err := parseInputFormat(tt.name)
+		if (err != nil) != tt.isError {
+			t.Errorf("parseInputFormat(%s) error = %v, wantErr %v", tt.name, err, tt.isError)
+		}
+		if format != tt.expected {
+			t.Errorf("parseInputFormat(%s) = %v, want %v", tt.name, format, tt.expected)
+		}
+	}
+}
+
+func TestRunIntergalacticConverterDocuments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		format   inputFormat
+		expected []string
+	}{
+		{
+			name: "Sentences and JSON documents mixed",
+			input: `{"op":"define_unit","unit":"glob","roman":"I"}
+prok is V
+{"op":"define_rate","units":["glob","glob"],"currency":"Silver","credits":34}
+{"op":"query_credits","units":["glob","prok"],"currency":"Silver"}
+how much is prok glob ?
+{"op":"query_value", "units":["glob",5]}
+{"op":"query_value","units":["wood"]}`,
+			format: autoInput,
+			expected: []string{
+				"glob prok silver is 68.00 Credits",
+				"prok glob is 6",
+				`script.txt:6:22: invalid document: field "units[1]": expected a unit, found 5`,
+				"script.txt:7:1: wood unit is not defined in the intergalactic database",
+			},
+		},
+		{
+			name: "JSON documents only",
+			input: `{"op":"define_unit","unit":"glob","roman":"I"}
+glob is I
+{"op":"query_value","units":["glob"],"colour":"red"}`,
+			format: jsonInput,
+			expected: []string{
+				"script.txt:2:1: invalid document: a JSON document starts with {",
+				`script.txt:3:38: invalid document: unknown field "colour"`,
+			},
+		},
+		{
+			name:   "Documents are sentences in text",
+			input:  `{"op":"begin"}`,
+			format: textInput,
+			expected: []string{
+				`script.txt:1:15: i have no idea what are you talking about: unexpected end of line, expected "is"`,
+			},
+		},
+		{
+			name: "YAML documents detected",
+			input: `# inventory
+op: define_unit
+unit: glob
+roman: I
+---
+op: define_rate
+units: [glob, glob]
+currency: Silver
+credits: 34
+---
+# the credits of a quantity
+op: query_credits
+units:
+  - glob
+  - glob
+currency: silver
+---
+op: query_value
+units: [glob]
+colour: red
+---
+op: query_credits
+units:
+  - glob
+  -   3
+currency: silver`,
+			format: autoInput,
+			expected: []string{
+				"glob glob silver is 34.00 Credits",
+				`script.txt:20:1: invalid document: unknown field "colour"`,
+				`script.txt:25:7: invalid document: field "units[1]": expected a unit, found "3"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := database.NewDatabase()
+			var output bytes.Buffer
+
+			err := runIntergalacticConverter(
+				calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "script.txt",
+				bytes.NewBufferString(tt.input), &output, sessionOptions{input: tt.format},
+			)
+			if err != nil {
+				t.Fatalf("runIntergalacticConverter() error = %v", err)
+			}
+
+			if got := outputLines(output.String()); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("runIntergalacticConverter() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -2,18 +2,115 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"math/big"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/erizkiatama/prospace-assignment/calculator"
+	"github.com/erizkiatama/prospace-assignment/constant"
 	"github.com/erizkiatama/prospace-assignment/database"
 	"github.com/erizkiatama/prospace-assignment/parser"
 )
 
-func runIntergalacticConverter(db database.Database, calc calculator.Calculator, reader io.Reader) []string {
-	responses := make([]string, 0)
+// scriptError locates an error in the input, compiler style
+type scriptError struct {
+	source string
+	line   int
+	column int
+	err    error
+}
+
+func (e *scriptError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.source, e.line, e.column, e.err)
+}
+
+func (e *scriptError) Unwrap() error {
+	return e.err
+}
+
+// sessionOptions are how a session reads statements and writes answers, the
+// zero value detects the input format and answers in text
+type sessionOptions struct {
+	input  inputFormat
+	output outputFormat
+	// explain adds the derivation of every answer
+	explain bool
+}
+
+// session answers statements one line at a time, counting lines to locate
+// errors. When source is not empty, errors are prefixed with
+// `source:line:col:` of the offending token.
+type session struct {
+	workspace calculator.Workspace
+	// journals keeps the changes of every namespace used, so undo works
+	// inside each of them
+	journals map[database.Database]*database.Journal
+	journal  *database.Journal
+	calc     calculator.Calculator
+	source   string
+	writer   io.Writer
+	sessionOptions
+	line int
+	// detected is set once the auto input format was settled
+	detected bool
+	// document gathers the lines of a YAML document, starting at documentLine
+	document     []string
+	documentLine int
+	// aborted is set when a statement of a block failed, the block was rolled
+	// back and its remaining statements are skipped until commit or rollback
+	aborted bool
+}
+
+// newSession starts in the default namespace of the workspace
+func newSession(
+	workspace calculator.Workspace, source string, writer io.Writer, options sessionOptions,
+) (*session, error) {
+	s := &session{
+		workspace:      workspace,
+		journals:       make(map[database.Database]*database.Journal),
+		source:         source,
+		writer:         writer,
+		sessionOptions: options,
+	}
+	return s, s.use("")
+}
+
+// use switches to the units and currencies of a namespace, a block has to
+// end first
+func (s *session) use(namespace string) error {
+	if s.aborted || (s.journal != nil && s.journal.InTransaction()) {
+		return constant.ErrInTransaction
+	}
+
+	db, calc, err := s.workspace(namespace, true)
+	if err != nil {
+		return err
+	}
+
+	journal, exists := s.journals[db]
+	if !exists {
+		journal = database.NewJournal(db)
+		s.journals[db] = journal
+	}
+	s.journal, s.calc = journal, calc
+	return nil
+}
+
+// runIntergalacticConverter answers every line read from reader, writing each
+// response to writer as soon as its line is processed.
+func runIntergalacticConverter(
+	workspace calculator.Workspace, source string, reader io.Reader, writer io.Writer, options sessionOptions,
+) error {
+	s, err := newSession(workspace, source, writer, options)
+	if err != nil {
+		return err
+	}
+	defer s.close()
 
 	scanner := bufio.NewScanner(reader)
 	for {
@@ -21,82 +118,739 @@ func runIntergalacticConverter(db database.Database, calc calculator.Calculator,
 		if !scanned {
 			break
 		}
-
-		line := scanner.Text()
-		if len(line) == 0 {
+		if !s.handleLine(scanner.Text()) {
 			break
 		}
+	}
+
+	return scanner.Err()
+}
+
+// close answers the last YAML document and rolls back a block that was never
+// committed
+func (s *session) close() {
+	s.flushDocument()
+	if s.aborted || s.journal.InTransaction() {
+		record := newRecord(parser.Control{Command: "rollback"})
+		if err := s.abort(); err != nil {
+			s.emit(record.failed(1, err))
+			return
+		}
+		record.Text = "transaction rolled back, it was never committed"
+		s.emit(record)
+	}
+}
+
+// handleLine answers a single line, it reports false once the session ends
+func (s *session) handleLine(line string) bool {
+	s.line++
+
+	trimmed := strings.TrimSpace(line)
+	s.detectInput(trimmed)
+	if s.input == yamlInput {
+		s.readYAML(line)
+		return true
+	}
+
+	switch {
+	case isSkippedLine(trimmed):
+		return true
+	case isExitCommand(trimmed):
+		return false
+	case strings.EqualFold(trimmed, "help"):
+		s.emit(record{Type: "help", answer: answer{Text: helpText}})
+		return true
+	}
+
+	// Columns are counted on the line as it was written, not the trimmed one
+	stmt, err := s.parseLine(line)
+	explained := s.explain
+	// A unit may be named explain too, the line is read as a whole when the
+	// rest is not a statement
+	if rest, ok := explainedLine(line); ok {
+		restStmt, restErr := s.parseLine(rest)
+		if restErr == nil || err != nil {
+			stmt, err = restStmt, restErr
+			line, explained = rest, true
+		}
+	}
+	s.handleStatement(line, stmt, err, explained)
+	return true
+}
+
+// handleStatement answers a parsed statement, or its parse error, with its
+// derivation when explained
+func (s *session) handleStatement(line string, stmt parser.Statement, err error, explained bool) {
+	if control, ok := stmt.(parser.Control); ok {
+		s.emit(s.control(line, control))
+		return
+	}
+	if use, ok := stmt.(parser.NamespaceUse); ok {
+		record := newRecord(use)
+		if err := s.use(use.Name); err != nil {
+			record = record.failed(errorColumn(line, err), err)
+		}
+		s.emit(record)
+		return
+	}
+	// The rest of a failed block is skipped, its error was already reported
+	if s.aborted {
+		record := newRecord(stmt)
+		record.Skipped = true
+		s.emit(record)
+		return
+	}
+	if err != nil {
+		column := errorColumn(line, err)
+		var syntaxErr *parser.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			column = syntaxErr.Column
+		}
+		s.emit(newRecord(nil).failed(column, err))
+		s.failBlock()
+		return
+	}
+
+	record := newRecord(stmt)
+	record.answer, err = execute(s.journal, s.calc, stmt)
+	if err == nil && explained {
+		record.Explanation, err = explain(s.journal, s.calc, stmt, record.basis)
+	}
+	// A statement failing part way outside a block leaves nothing behind
+	if endErr := s.journal.EndStatement(err != nil); endErr != nil && err == nil {
+		err = endErr
+	}
+	if err != nil {
+		s.emit(record.failed(errorColumn(line, err), err))
+		s.failBlock()
+		return
+	}
+	s.emit(record)
+}
+
+// control runs the statements grouping or taking back changes, they have no
+// response unless they fail
+func (s *session) control(line string, stmt parser.Control) record {
+	record := newRecord(stmt)
+
+	var err error
+	switch stmt.Command {
+	case "begin":
+		if s.aborted {
+			err = constant.ErrInTransaction
+		} else {
+			err = s.journal.Begin()
+		}
+	case "commit", "rollback":
+		if s.aborted {
+			s.aborted = false
+			if stmt.Command == "commit" {
+				record.Text = "transaction rolled back after an error"
+			}
+			return record
+		}
+		if stmt.Command == "commit" {
+			err = s.journal.Commit()
+		} else {
+			err = s.journal.Rollback()
+		}
+	case "undo":
+		err = s.journal.Undo()
+	case "redo":
+		err = s.journal.Redo()
+	}
 
-		// Process the line here
-		parsed := parser.Parse(strings.ToLower(line))
-		switch parsed.InputType {
-		case parser.Assignment:
-			if parsed.ItemType == parser.Roman {
-				db.AddUnitToRomanMapping(parsed.FirstToken[0], parsed.FirstToken[2])
-			} else {
-				unitResult, err := calc.ConvertUnitsToInt(parsed.FirstToken)
-				if err != nil {
-					responses = append(responses, err.Error())
-					break
-				}
-				db.AddCurrencyToCreditsMapping(parsed.FirstCurrency, float32(parsed.Credits)/float32(unitResult))
+	if err != nil {
+		return record.failed(errorColumn(line, err), err)
+	}
+	return record
+}
+
+// failBlock rolls back the block a statement failed in
+func (s *session) failBlock() {
+	if !s.journal.InTransaction() {
+		return
+	}
+	if err := s.abort(); err != nil {
+		s.emit(newRecord(parser.Control{Command: "rollback"}).failed(1, err))
+	}
+}
+
+// abort takes back the changes of the current block, its remaining statements
+// are skipped until it ends
+func (s *session) abort() error {
+	s.aborted = true
+	if s.journal.InTransaction() {
+		return s.journal.Rollback()
+	}
+	return nil
+}
+
+// execute runs a single statement, definitions have no text answer unless
+// they change an existing one
+func execute(db database.Database, calc calculator.Calculator, stmt parser.Statement) (answer, error) {
+	switch stmt := stmt.(type) {
+	case parser.UnitDefinition:
+		symbol := calculator.CanonicalSymbol(strings.ToUpper(stmt.Symbol))
+		if !calculator.IsNumeralSymbol(symbol) {
+			return answer{}, &constant.InvalidNumeralError{
+				Numeral: symbol, Position: 1, Rule: fmt.Sprintf("%s is not a numeral symbol", symbol),
 			}
-		case parser.Calculation:
-			if parsed.ItemType == parser.Roman {
-				result, err := calc.ConvertUnitsToInt(parsed.FirstToken)
-				if err != nil {
-					responses = append(responses, err.Error())
-					break
-				}
-				responses = append(responses, fmt.Sprintf("%s is %d", strings.Join(parsed.FirstToken, " "), result))
-			} else {
-				unitResult, err := calc.ConvertUnitsToInt(parsed.FirstToken)
-				if err != nil {
-					responses = append(responses, err.Error())
-					break
-				}
-				result, err := calc.CalculateCreditsCurrency(float32(unitResult), parsed.FirstCurrency)
-				if err != nil {
-					responses = append(responses, err.Error())
-					break
-				}
-				responses = append(responses, fmt.Sprintf(
-					"%s %s is %.2f Credits",
-					strings.Join(parsed.FirstToken, " "), parsed.FirstCurrency, result,
-				))
+		}
+		// Rates defined with the unit follow its new value
+		stale, err := db.DefineUnit(stmt.Unit, symbol, calc.RateFromSymbols)
+		warning, err := constant.SplitWarning(err)
+		if err != nil {
+			return answer{}, err
+		}
+		return answer{
+			Text:    warningText(warning),
+			Result:  unitResult{Unit: stmt.Unit, Symbol: symbol},
+			Warning: warning,
+			Stale:   stale,
+		}, nil
+	case parser.RateDefinition:
+		// The sentence is kept so the rate can follow later changes of its units
+		source := database.RateSource{Units: stmt.Units, Credits: stmt.Credits}
+		rate, err := db.DefineRate(stmt.Currency, source, calc.RateFromSymbols)
+		warning, err := constant.SplitWarning(err)
+		if err != nil {
+			return answer{}, err
+		}
+		return answer{
+			Text:    warningText(warning),
+			Result:  rateResult{Currency: stmt.Currency, Rate: rate.RatString()},
+			Roman:   []string{romanOf(db, stmt.Units)},
+			Warning: warning,
+		}, nil
+	case parser.NumeralQuery:
+		if stmt.Expression != nil {
+			return executeExpression(db, calc, stmt.Expression)
+		}
+		result, err := calc.ConvertUnitsToInt(stmt.Units)
+		if err != nil {
+			return answer{}, err
+		}
+		return answer{
+			Text:   fmt.Sprintf("%s is %d", strings.Join(stmt.Units, " "), result),
+			Result: valueResult{Value: result},
+			Roman:  []string{romanOf(db, stmt.Units)},
+		}, nil
+	case parser.CreditQuery:
+		return executeCreditQuery(db, calc, stmt)
+	case parser.ConversionQuery:
+		return executeConversion(db, calc, stmt)
+	case parser.PurchaseQuery:
+		return executePurchase(db, calc, stmt)
+	case parser.TranslationQuery:
+		result, err := calc.ConvertIntToUnits(stmt.Number, stmt.System)
+		if err != nil {
+			return answer{}, err
+		}
+		return answer{
+			Text:   fmt.Sprintf("%d is %s", stmt.Number, strings.Join(result, " ")),
+			Result: unitsResult{Units: result},
+			Roman:  []string{romanOf(db, result)},
+		}, nil
+	case parser.UnitComparison:
+		result, err := calc.CompareTwoUnits(stmt.First, stmt.Second)
+		if err != nil {
+			return answer{}, err
+		}
+		return answer{
+			Text: fmt.Sprintf("%s is %s %s",
+				strings.Join(stmt.First, " "), result, strings.Join(stmt.Second, " "),
+			),
+			Result: comparisonResult{Comparison: result},
+			Roman:  []string{romanOf(db, stmt.First), romanOf(db, stmt.Second)},
+		}, nil
+	case parser.CreditComparison:
+		units := append(append([]string{}, stmt.First.Units...), stmt.Second.Units...)
+		db, calc := readAtOnce(db, calc, units, []string{stmt.First.Currency, stmt.Second.Currency})
+		result, err := calc.CompareTwoCurrency(stmt.First.Units, stmt.Second.Units, stmt.First.Currency, stmt.Second.Currency)
+		if err != nil {
+			return answer{}, err
+		}
+		stale := staleCurrencies(db, stmt.First.Currency, stmt.Second.Currency)
+		return answer{
+			Text: fmt.Sprintf("%s %s %s %s %s%s",
+				strings.Join(stmt.First.Units, " "),
+				stmt.First.Currency,
+				result,
+				strings.Join(stmt.Second.Units, " "),
+				stmt.Second.Currency,
+				staleNote(stale),
+			),
+			Result: comparisonResult{Comparison: result},
+			Roman:  []string{romanOf(db, stmt.First.Units), romanOf(db, stmt.Second.Units)},
+			Stale:  stale,
+			basis:  basis{db: db, calc: calc},
+		}, nil
+	case parser.Removal:
+		// Rates defined with a forgotten unit are flagged stale
+		stale, err := forget(db, calc, stmt.Name)
+		if err != nil {
+			return answer{}, err
+		}
+		return answer{Stale: stale}, nil
+	case parser.Listing:
+		if stmt.Kind == "history" {
+			return history(db, stmt.Currency)
+		}
+		return listing(db, stmt.Kind)
+	default:
+		return answer{}, fmt.Errorf("unsupported statement %T", stmt)
+	}
+}
+
+// warningText is the answer to a definition applied with a warning, nothing
+// when there is none
+func warningText(warning string) string {
+	if warning == "" {
+		return ""
+	}
+	return "warning: " + warning
+}
+
+// forget removes the unit and the currency with the name, it is an error only
+// when there is neither. The currencies left stale by the unit are returned.
+func forget(db database.Database, calc calculator.Calculator, name string) ([]string, error) {
+	stale, unitErr := db.ForgetUnit(name, calc.RateFromSymbols)
+	currencyErr := db.RemoveCurrency(name)
+
+	switch {
+	case unitErr != nil && !errors.Is(unitErr, constant.ErrNotDefined):
+		return nil, unitErr
+	case currencyErr != nil && !errors.Is(currencyErr, constant.ErrNotDefined):
+		return nil, currencyErr
+	case unitErr != nil && currencyErr != nil:
+		return nil, &constant.UnknownNameError{Name: strings.ToLower(name)}
+	}
+	return stale, nil
+}
+
+// listing answers a line per defined unit or currency
+func listing(db database.Database, kind string) (answer, error) {
+	lines := make([]string, 0)
+	var result any
+	if kind == "units" {
+		units := make([]unitResult, 0)
+		for _, unit := range db.ListUnits() {
+			symbol, err := db.GetRomanFromUnit(unit)
+			if err != nil {
+				return answer{}, err
 			}
-		case parser.Comparison:
-			if parsed.ItemType == parser.Roman {
-				result, err := calc.CompareTwoUnits(parsed.FirstToken, parsed.SecondToken)
-				if err != nil {
-					responses = append(responses, err.Error())
-					break
-				}
-				responses = append(responses, fmt.Sprintf("%s is %s %s",
-					strings.Join(parsed.FirstToken, " "), result, strings.Join(parsed.SecondToken, " ")),
-				)
-			} else {
-				result, err := calc.CompareTwoCurrency(parsed.FirstToken, parsed.SecondToken, parsed.FirstCurrency, parsed.SecondCurrency)
-				if err != nil {
-					responses = append(responses, err.Error())
-					break
-				}
-				responses = append(responses, fmt.Sprintf("%s %s %s %s %s",
-					strings.Join(parsed.FirstToken, " "),
-					parsed.FirstCurrency,
-					result,
-					strings.Join(parsed.SecondToken, " "),
-					parsed.SecondCurrency),
-				)
+			lines = append(lines, fmt.Sprintf("%s is %s", unit, symbol))
+			units = append(units, unitResult{Unit: unit, Symbol: symbol})
+		}
+		result = units
+	} else {
+		rates := make([]rateResult, 0)
+		for _, currency := range db.ListCurrencies() {
+			credits, err := db.GetCreditsFromCurrency(currency)
+			if err != nil {
+				return answer{}, err
 			}
-		default:
-			responses = append(responses, parsed.Error.Error())
+			stale := staleCurrencies(db, currency)
+			lines = append(lines, fmt.Sprintf("1 %s is %s Credits%s", currency, credits.FloatString(2), staleNote(stale)))
+			rates = append(rates, rateResult{Currency: currency, Rate: credits.RatString(), Stale: len(stale) > 0})
+		}
+		result = rates
+	}
+
+	if len(lines) == 0 {
+		return answer{Text: fmt.Sprintf("no %s defined", kind), Result: result}, nil
+	}
+	return answer{Text: strings.Join(lines, "\n"), Result: result}, nil
+}
+
+// history answers a line per rate a currency had, oldest first
+func history(db database.Database, currency string) (answer, error) {
+	points, err := db.GetRateHistory(currency)
+	if err != nil {
+		return answer{}, err
+	}
+
+	lines := make([]string, len(points))
+	result := make([]ratePointResult, len(points))
+	for i, point := range points {
+		// Rates kept from before history was recorded have no time
+		since := "from the start"
+		result[i].Rate = point.Credits.RatString()
+		if !point.Time.IsZero() {
+			result[i].Since = point.Time.Format(time.RFC3339)
+			since = "since " + result[i].Since
+		}
+		lines[i] = fmt.Sprintf("1 %s is %s Credits %s", strings.ToLower(currency), point.Credits.FloatString(2), since)
+	}
+	return answer{Text: strings.Join(lines, "\n"), Result: result}, nil
+}
+
+// executeExpression answers the value of an expression, and its units when
+// the value can be written with them
+func executeExpression(db database.Database, calc calculator.Calculator, expr parser.Expression) (answer, error) {
+	result, err := calc.EvaluateExpression(expr)
+	if err != nil {
+		return answer{}, err
+	}
+
+	text := fmt.Sprintf("%s is %d", expr, result)
+	units := expressionUnits(calc, expr, result)
+	if len(units) > 0 {
+		text += fmt.Sprintf(" (%s)", strings.Join(units, " "))
+	}
+	return answer{
+		Text:   text,
+		Result: valueResult{Value: result, Units: units},
+		Roman:  expressionRoman(db, expr),
+	}, nil
+}
+
+// expressionUnits writes the value of an expression back as units, in the
+// numeral system of its first operand. There are none when the system can not
+// write the value, e.g. a negative one, or when a symbol it needs has no unit.
+func expressionUnits(calc calculator.Calculator, expr parser.Expression, value int) []string {
+	derivation, err := calc.DeriveUnits(parser.Operands(expr)[0].Units)
+	if err != nil {
+		return nil
+	}
+	units, err := calc.ConvertIntToUnits(value, derivation.System)
+	if err != nil {
+		return nil
+	}
+	return units
+}
+
+// expressionRoman is the numeral of every operand of an expression
+func expressionRoman(db database.Database, expr parser.Expression) []string {
+	operands := parser.Operands(expr)
+	roman := make([]string, len(operands))
+	for i, operand := range operands {
+		roman[i] = romanOf(db, operand.Units)
+	}
+	return roman
+}
+
+func executeCreditQuery(db database.Database, calc calculator.Calculator, stmt parser.CreditQuery) (answer, error) {
+	db, calc = readAtOnce(db, calc, creditQueryUnits(stmt), []string{stmt.Currency})
+	unitResult, quantityText, roman, err := creditQueryAmount(db, calc, stmt)
+	if err != nil {
+		return answer{}, err
+	}
+
+	if stmt.At != nil {
+		result, err := calc.CalculateCreditsCurrencyOn(big.NewRat(int64(unitResult), 1), stmt.Currency, *stmt.At)
+		if err != nil {
+			return answer{}, err
+		}
+		return answer{
+			Text: fmt.Sprintf(
+				"%s %s was %s Credits on %s",
+				quantityText, stmt.Currency, result.FloatString(2), stmt.At.Format(time.DateOnly),
+			),
+			Result: creditsResult{Credits: result.FloatString(2)},
+			Roman:  roman,
+			basis:  basis{db: db, calc: calc},
+		}, nil
+	}
+
+	result, err := calc.CalculateCreditsCurrency(big.NewRat(int64(unitResult), 1), stmt.Currency)
+	if err != nil {
+		return answer{}, err
+	}
+	stale := staleCurrencies(db, stmt.Currency)
+	return answer{
+		Text: fmt.Sprintf(
+			"%s %s is %s Credits%s",
+			quantityText, stmt.Currency, result.FloatString(2), staleNote(stale),
+		),
+		Result: creditsResult{Credits: result.FloatString(2)},
+		Roman:  roman,
+		Stale:  stale,
+		basis:  basis{db: db, calc: calc},
+	}, nil
+}
+
+// readAtOnce reads the units and currencies of a query at once, the database
+// and calculator returned answer for them from that single read
+func readAtOnce(db database.Database, calc calculator.Calculator, units, currencies []string) (database.Database, calculator.Calculator) {
+	db = database.ReadAtOnce(db, units, currencies)
+	return db, calc.With(db)
+}
+
+// creditQueryAmount computes the quantity of a credit query, with how the
+// answer writes it and the numerals of its units. An expression is written in
+// parentheses so it stays apart from the currency.
+func creditQueryAmount(db database.Database, calc calculator.Calculator, stmt parser.CreditQuery) (int, string, []string, error) {
+	if stmt.Expression == nil {
+		result, err := calc.ConvertUnitsToInt(stmt.Units)
+		if err != nil {
+			return 0, "", nil, err
+		}
+		text := strings.Join(stmt.Units, " ")
+		if err := positiveQuantity(result, text); err != nil {
+			return 0, "", nil, err
+		}
+		return result, text, []string{romanOf(db, stmt.Units)}, nil
+	}
+
+	result, err := calc.EvaluateExpression(stmt.Expression)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	if err := positiveQuantity(result, stmt.Expression.String()); err != nil {
+		return 0, "", nil, err
+	}
+	text := stmt.Expression.String()
+	if _, ok := stmt.Expression.(parser.Operation); ok {
+		text = "(" + text + ")"
+	}
+	return result, text, expressionRoman(db, stmt.Expression), nil
+}
+
+// creditQueryUnits lists the units of a credit query, those of every operand
+// of its expression
+func creditQueryUnits(stmt parser.CreditQuery) []string {
+	if stmt.Expression == nil {
+		return stmt.Units
+	}
+	var units []string
+	for _, operand := range parser.Operands(stmt.Expression) {
+		units = append(units, operand.Units...)
+	}
+	return units
+}
+
+// positiveQuantity refuses a quantity of a currency that is negative or
+// nothing, unlike a value, e.g. the Mayan 0
+func positiveQuantity(result int, text string) error {
+	if result <= 0 {
+		return &constant.ArithmeticError{Operation: text, Reason: "a quantity of a currency has to be positive"}
+	}
+	return nil
+}
+
+func executeConversion(db database.Database, calc calculator.Calculator, stmt parser.ConversionQuery) (answer, error) {
+	db, calc = readAtOnce(db, calc, stmt.Units, []string{stmt.Currency, stmt.Target})
+	quantity := stmt.Amount
+	quantityText := ""
+	var roman []string
+	if quantity != nil {
+		quantityText = formatQuantity(quantity)
+	} else {
+		unitResult, err := calc.ConvertUnitsToInt(stmt.Units)
+		if err != nil {
+			return answer{}, err
 		}
+		quantity = big.NewRat(int64(unitResult), 1)
+		quantityText = strings.Join(stmt.Units, " ")
+		roman = []string{romanOf(db, stmt.Units)}
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+	// Credits are not a currency in the database, they are what rates are in
+	if strings.EqualFold(stmt.Target, "credits") {
+		result, err := calc.CalculateCreditsCurrency(quantity, stmt.Currency)
+		if err != nil {
+			return answer{}, err
+		}
+		stale := staleCurrencies(db, stmt.Currency)
+		return answer{
+			Text: fmt.Sprintf(
+				"%s %s is %s Credits%s", quantityText, stmt.Currency, result.FloatString(2), staleNote(stale),
+			),
+			Result: creditsResult{Credits: result.FloatString(2)},
+			Roman:  roman,
+			Stale:  stale,
+			basis:  basis{db: db, calc: calc},
+		}, nil
+	}
+
+	result, err := calc.ConvertCurrency(quantity, stmt.Currency, stmt.Target)
+	if err != nil {
+		return answer{}, err
+	}
+
+	text := fmt.Sprintf("%s %s is %s %s", quantityText, stmt.Currency, formatQuantity(result), stmt.Target)
+	conversion := conversionResult{Amount: formatQuantity(result), Currency: stmt.Target}
+
+	// A whole quantity is also said in units, when every symbol is assigned
+	if result.IsInt() && result.Num().IsInt64() {
+		if units, err := calc.ConvertIntToUnits(int(result.Num().Int64()), ""); err == nil {
+			text += fmt.Sprintf(" (%s %s)", strings.Join(units, " "), stmt.Target)
+			conversion.Units = units
+			roman = append(roman, romanOf(db, units))
+		}
+	}
+
+	stale := staleCurrencies(db, stmt.Currency, stmt.Target)
+	return answer{
+		Text:   text + staleNote(stale),
+		Result: conversion,
+		Roman:  roman,
+		Stale:  stale,
+		basis:  basis{db: db, calc: calc},
+	}, nil
+}
+
+// executePurchase answers how many whole units of a currency a budget buys,
+// said in units too when every symbol is assigned, and the credits left
+func executePurchase(db database.Database, calc calculator.Calculator, stmt parser.PurchaseQuery) (answer, error) {
+	db, calc = readAtOnce(db, calc, nil, []string{stmt.Currency})
+	quantity, remainder, rate, err := calc.CalculatePurchase(stmt.Credits, stmt.Currency)
+	if err != nil {
+		return answer{}, err
+	}
+
+	quantityText := strconv.Itoa(quantity)
+	purchase := purchaseResult{Quantity: quantity, Currency: stmt.Currency, Remainder: remainder.FloatString(2)}
+	var roman []string
+	if units, err := calc.ConvertIntToUnits(quantity, ""); err == nil {
+		quantityText += fmt.Sprintf(" %s (%s %s)", stmt.Currency, strings.Join(units, " "), stmt.Currency)
+		purchase.Units = units
+		roman = []string{romanOf(db, units)}
+	} else {
+		quantityText += " " + stmt.Currency
+	}
+
+	stale := staleCurrencies(db, stmt.Currency)
+	return answer{
+		Text: fmt.Sprintf("%s Credits buy %s, %s Credits left%s",
+			formatQuantity(stmt.Credits), quantityText, remainder.FloatString(2), staleNote(stale),
+		),
+		Result: purchase,
+		Roman:  roman,
+		Stale:  stale,
+		basis:  basis{db: db, calc: calc, bought: &bought{quantity: quantity, rate: rate, left: remainder}},
+	}, nil
+}
+
+// staleCurrencies finds the currencies among the given ones whose rate could
+// not follow a change of their units
+func staleCurrencies(db database.Database, currencies ...string) []string {
+	stale := make([]string, 0)
+	for _, currency := range currencies {
+		currency = strings.ToLower(currency)
+		if source, err := db.GetRateSource(currency); err == nil && source.Stale && !slices.Contains(stale, currency) {
+			stale = append(stale, currency)
+		}
+	}
+	return stale
+}
+
+// staleNote flags an answer computed with stale rates
+func staleNote(stale []string) string {
+	if len(stale) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (stale rate for %s)", strings.Join(stale, " and "))
+}
+
+// romanOf writes the symbols assigned to units as a single numeral
+func romanOf(db database.Database, units []string) string {
+	symbols := make([]string, 0, len(units))
+	for _, unit := range units {
+		symbol, err := db.GetRomanFromUnit(unit)
+		if err != nil {
+			return ""
+		}
+		symbols = append(symbols, symbol)
+	}
+	return numeralText(symbols)
+}
+
+// formatQuantity writes whole quantities as integers, and the others as
+// decimals rounded to 6 digits after point. A smaller quantity keeps 5
+// significant digits instead, so it is never rounded away.
+func formatQuantity(quantity *big.Rat) string {
+	if quantity.IsInt() {
+		return quantity.RatString()
+	}
+
+	// The first digit after point that is not zero once rounded
+	first := 1
+	for strings.Trim(quantity.FloatString(first), "-0.") == "" {
+		first++
+	}
+	digits := max(6, first+4)
+
+	decimal := strings.TrimRight(quantity.FloatString(digits), "0")
+	if strings.HasSuffix(decimal, ".") {
+		decimal += "0"
 	}
+	return decimal
+}
+
+// errorColumn finds the column of the first token an error is about, falling
+// back to the start of the statement
+func errorColumn(line string, err error) int {
+	var fieldErr *parser.FieldError
+	if errors.As(err, &fieldErr) {
+		if fieldErr.Column > 0 {
+			return fieldErr.Column
+		}
+		if column := fieldColumn(line, fieldErr.Field); column > 0 {
+			return column
+		}
+	}
+
+	target := ""
+	var unitErr *constant.UnknownUnitError
+	var currencyErr *constant.UnknownCurrencyError
+	var noRateErr *constant.NoRateError
+	var unknownNamespaceErr *constant.UnknownNamespaceError
+	var invalidNamespaceErr *constant.InvalidNamespaceError
+	switch {
+	case errors.As(err, &unitErr):
+		target = unitErr.Unit
+	case errors.As(err, &currencyErr):
+		target = currencyErr.Currency
+	case errors.As(err, &noRateErr):
+		target = noRateErr.Currency
+	case errors.As(err, &unknownNamespaceErr):
+		target = unknownNamespaceErr.Namespace
+	case errors.As(err, &invalidNamespaceErr):
+		target = invalidNamespaceErr.Namespace
+	}
+
+	tokens := parser.Lex(line)
+	for _, token := range tokens {
+		if target != "" && token.Kind == parser.Word && strings.EqualFold(token.Text, target) {
+			return token.Pos
+		}
+	}
+	return tokens[0].Pos
+}
+
+const helpText = `Supported sentences, where {units} are unit words like "glob prok":
+  {unit} is {symbol}                                  assign a numeral symbol to a unit
+  {units} {currency} is {number} Credits              set the credits of a currency
+  how much is {units} ?                               value of units
+  how much is {units} plus|minus|times|divided by {units} ?
+                                                      value of an expression, {units} may be in ( )
+  how many Credits is {units} {currency} ?            credits of a quantity, or of an expression
+  how many Credits was {units} {currency} at {date} ? credits on a day, as YYYY-MM-DD
+  how many {currency} is {units|number} {currency} ?  convert between currencies
+  how many {currency} can I buy with {number} Credits ?
+                                                      whole quantity a budget buys, and the credits left
+  how do you say {number} [in {system}] ?             units of a number
+  is {units} larger|smaller than {units} ?            compare two values
+  does {units} {currency} has more|less Credits than {units} {currency} ?
+                                                      compare two credits
+  forget {name}                                       remove a unit or a currency
+  list units|currencies                               show every unit or currency
+  list history of {currency}                          show every rate of a currency
+  begin | commit | rollback                           group changes, kept or taken back together
+  undo | redo                                         take back or apply again the last change
+  use namespace {name}                                switch to the units and currencies of a namespace
+  help                                                show this help
+  quit | exit                                         end the session`
+
+// Blank lines and comments are skipped so scripts can be split into sections
+func isSkippedLine(line string) bool {
+	return len(line) == 0 || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//")
+}
 
-	return responses
+func isExitCommand(line string) bool {
+	line = strings.ToLower(line)
+	return line == "quit" || line == "exit"
 }
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
-	"github.com/erizkiatama/prospace-assignment/constant"
+	"errors"
+	"io"
+	"math/big"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/calculator"
+	"github.com/erizkiatama/prospace-assignment/constant"
+	"github.com/erizkiatama/prospace-assignment/database"
+	"github.com/erizkiatama/prospace-assignment/parser"
 )
 
 // MockDatabase implements the Database interface for testing
@@ -12,19 +22,100 @@ type MockDatabase struct {
 	isError bool
 }
 
-func (m *MockDatabase) AddUnitToRomanMapping(unit, roman string)                     {}
-func (m *MockDatabase) AddCurrencyToCreditsMapping(currency string, credits float32) {}
 func (m *MockDatabase) GetRomanFromUnit(unit string) (string, error) {
 	if m.isError {
 		return "", constant.ErrInvalidFormat
 	}
 	return "I", nil
 }
-func (m *MockDatabase) GetCreditsFromCurrency(currency string) (float32, error) {
+func (m *MockDatabase) GetUnitFromRoman(roman string) (string, error) {
 	if m.isError {
-		return 0, constant.ErrInvalidFormat
+		return "", constant.ErrInvalidFormat
+	}
+	return "glob", nil
+}
+func (m *MockDatabase) GetCreditsFromCurrency(currency string) (*big.Rat, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	return big.NewRat(1, 1), nil
+}
+func (m *MockDatabase) GetCreditsFromCurrencies(currencies ...string) ([]*big.Rat, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	rates := make([]*big.Rat, len(currencies))
+	for i := range currencies {
+		rates[i] = big.NewRat(1, 1)
+	}
+	return rates, nil
+}
+func (m *MockDatabase) Read(units, currencies []string) database.Reading {
+	reading := database.Reading{
+		Symbols: make(map[string]string),
+		Rates:   make(map[string]*big.Rat),
+		Sources: make(map[string]database.RateSource),
+	}
+	if m.isError {
+		return reading
+	}
+	for _, unit := range units {
+		reading.Symbols[strings.ToLower(unit)] = "I"
+	}
+	for _, currency := range currencies {
+		reading.Rates[strings.ToLower(currency)] = big.NewRat(1, 1)
+	}
+	return reading
+}
+func (m *MockDatabase) RemoveCurrency(currency string) error {
+	if m.isError {
+		return constant.ErrInvalidFormat
+	}
+	return nil
+}
+func (m *MockDatabase) DefineUnit(unit, roman string, rate database.RateFunc) ([]string, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	return []string{}, nil
+}
+func (m *MockDatabase) ForgetUnit(unit string, rate database.RateFunc) ([]string, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
 	}
-	return 1.0, nil
+	return []string{}, nil
+}
+func (m *MockDatabase) DefineRate(currency string, source database.RateSource, rate database.RateFunc) (*big.Rat, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	return rate([]string{"I"}, source.Credits)
+}
+func (m *MockDatabase) GetRateSource(currency string) (database.RateSource, error) {
+	return database.RateSource{}, &constant.UnknownCurrencyError{Currency: currency}
+}
+func (m *MockDatabase) GetCreditsFromCurrencyAt(currency string, at time.Time) (*big.Rat, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	return big.NewRat(1, 1), nil
+}
+func (m *MockDatabase) GetRateHistory(currency string) ([]database.RatePoint, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	return []database.RatePoint{{Credits: big.NewRat(1, 1)}}, nil
+}
+
+func (m *MockDatabase) ListUnits() []string      { return []string{"glob"} }
+func (m *MockDatabase) ListCurrencies() []string { return []string{"silver"} }
+
+func (m *MockDatabase) Snapshot(scope database.Scope) database.Snapshot { return database.Snapshot{} }
+func (m *MockDatabase) Restore(snapshot database.Snapshot) error {
+	if m.isError {
+		return constant.ErrInvalidFormat
+	}
+	return nil
 }
 
 // MockCalculator implements the Calculator interface for testing
@@ -38,11 +129,41 @@ func (m *MockCalculator) ConvertUnitsToInt(units []string) (int, error) {
 	}
 	return 1, nil
 }
-func (m *MockCalculator) CalculateCreditsCurrency(unit float32, currency string) (float32, error) {
+func (m *MockCalculator) DeriveUnits(units []string) (calculator.Derivation, error) {
+	if m.isError {
+		return calculator.Derivation{}, constant.ErrInvalidFormat
+	}
+	return calculator.Derivation{Units: units, Value: 1}, nil
+}
+func (m *MockCalculator) EvaluateExpression(expr parser.Expression) (int, error) {
 	if m.isError {
 		return 0, constant.ErrInvalidFormat
 	}
-	return 1.0, nil
+	return 1, nil
+}
+func (m *MockCalculator) CalculatePurchase(credits *big.Rat, currency string) (int, *big.Rat, *big.Rat, error) {
+	if m.isError {
+		return 0, nil, nil, constant.ErrInvalidFormat
+	}
+	return 1, big.NewRat(0, 1), big.NewRat(1, 1), nil
+}
+func (m *MockCalculator) ConvertIntToUnits(number int, system string) ([]string, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	return []string{"glob"}, nil
+}
+func (m *MockCalculator) CalculateCreditsCurrency(unit *big.Rat, currency string) (*big.Rat, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	return big.NewRat(1, 1), nil
+}
+func (m *MockCalculator) CalculateCreditsCurrencyOn(unit *big.Rat, currency string, day time.Time) (*big.Rat, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	return big.NewRat(1, 1), nil
 }
 func (m *MockCalculator) CompareTwoUnits(first, second []string) (string, error) {
 	if m.isError {
@@ -57,6 +178,22 @@ func (m *MockCalculator) CompareTwoCurrency(first, second []string, firstCurrenc
 	return "has less credits than", nil
 }
 
+func (m *MockCalculator) ConvertCurrency(quantity *big.Rat, from, to string) (*big.Rat, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	return big.NewRat(1, 1), nil
+}
+func (m *MockCalculator) RateFromSymbols(symbols []string, credits *big.Rat) (*big.Rat, error) {
+	if m.isError {
+		return nil, constant.ErrInvalidFormat
+	}
+	return big.NewRat(1, 1), nil
+}
+func (m *MockCalculator) With(db database.Database) calculator.Calculator {
+	return m
+}
+
 func TestRunIntergalacticConverter(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -94,16 +231,62 @@ func TestRunIntergalacticConverter(t *testing.T) {
 			input:    "does glob prok Silver has less credits than glob prok Gold ?\n",
 			expected: []string{"glob prok silver has less credits than glob prok gold"},
 		},
+		{
+			name:     "Currency conversion",
+			input:    "how many Gold is glob glob Silver ?\n",
+			expected: []string{"glob glob silver is 1 gold (glob gold)"},
+		},
+		{
+			name:     "Currency conversion of a number",
+			input:    "how many Gold is 10.5 Silver ?\n",
+			expected: []string{"10.5 silver is 1 gold (glob gold)"},
+		},
+		{
+			name:     "Currency conversion to credits",
+			input:    "how many Credits is 10 Silver ?\n",
+			expected: []string{"10 silver is 1.00 Credits"},
+		},
+		{
+			name:     "Number translation",
+			input:    "how do you say 1 ?\n",
+			expected: []string{"1 is glob"},
+		},
 		{
 			name:     "Invalid input",
 			input:    "how much wood could a woodchuck chuck if a woodchuck could chuck wood ?\n",
-			expected: []string{constant.ErrInvalidParse.Error()},
+			expected: []string{constant.ErrInvalidParse.Error() + `: unexpected "wood", expected "is"`},
 		},
 		{
 			name:     "empty input",
 			input:    "\n",
 			expected: []string{},
 		},
+		{
+			name:     "blank lines and comments are skipped",
+			input:    "# units\nglob is I\n\n   \n// questions\nhow much is glob ?\n\nhow much is glob glob ?\n",
+			expected: []string{"glob is 1", "glob glob is 1"},
+		},
+		{
+			name:     "quit ends the session",
+			input:    "how much is glob ?\nQUIT\nhow much is glob glob ?\n",
+			expected: []string{"glob is 1"},
+		},
+		{
+			name:     "exit ends the session",
+			input:    "exit\nhow much is glob ?\n",
+			expected: []string{},
+		},
+		{
+			name:     "help lists the sentences",
+			input:    "HELP\n",
+			expected: strings.Split(helpText, "\n"),
+		},
+		{
+			name:     "error on roman numeral assignment",
+			input:    "glob is I\n",
+			expected: []string{constant.ErrInvalidFormat.Error()},
+			hasError: true,
+		},
 		{
 			name:     "error on assignment",
 			input:    "xyz xyz is 34 credits\n",
@@ -122,6 +305,18 @@ func TestRunIntergalacticConverter(t *testing.T) {
 			expected: []string{constant.ErrInvalidFormat.Error()},
 			hasError: true,
 		},
+		{
+			name:     "error on currency conversion",
+			input:    "how many Gold is 10 Silver ?\n",
+			expected: []string{constant.ErrInvalidFormat.Error()},
+			hasError: true,
+		},
+		{
+			name:     "error on number translation",
+			input:    "how do you say 1944 ?\n",
+			expected: []string{constant.ErrInvalidFormat.Error()},
+			hasError: true,
+		},
 		{
 			name:     "error on roman numeral comparison",
 			input:    "is pish smaller than glob ?\n",
@@ -148,12 +343,550 @@ func TestRunIntergalacticConverter(t *testing.T) {
 			calc := &MockCalculator{
 				isError: tt.hasError,
 			}
-			got := runIntergalacticConverter(db, calc, input)
+			var output bytes.Buffer
+			if err := runIntergalacticConverter(calculator.SingleWorkspace(db, calc), "", input, &output, sessionOptions{}); err != nil {
+				t.Fatalf("runIntergalacticConverter() error = %v", err)
+			}
 
 			// Check the output
+			got := outputLines(output.String())
 			if !reflect.DeepEqual(got, tt.expected) {
 				t.Errorf("runIntergalacticConverter() = %v, want %v", got, tt.expected)
 			}
 		})
 	}
 }
+
+func outputLines(output string) []string {
+	output = strings.TrimSuffix(output, "\n")
+	if output == "" {
+		return []string{}
+	}
+	return strings.Split(output, "\n")
+}
+
+func TestRunIntergalacticConverterStreamsResponses(t *testing.T) {
+	inputReader, inputWriter := io.Pipe()
+	outputReader, outputWriter := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runIntergalacticConverter(calculator.SingleWorkspace(&MockDatabase{}, &MockCalculator{}), "", inputReader, outputWriter, sessionOptions{})
+		outputWriter.Close()
+	}()
+
+	// The answer must be readable while the input is still open
+	go io.WriteString(inputWriter, "how much is glob ?\n")
+	response, err := bufio.NewReader(outputReader).ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+	if response != "glob is 1\n" {
+		t.Errorf("response = %q, want %q", response, "glob is 1\n")
+	}
+
+	inputWriter.Close()
+	if err := <-done; err != nil {
+		t.Errorf("runIntergalacticConverter() error = %v", err)
+	}
+}
+
+func TestRunIntergalacticConverterLocatesErrors(t *testing.T) {
+	input := bytes.NewBufferString("# script\nglob is I\n\nhow much wood ?\n  how much is glob ?\nglob glob Silver is lots Credits\n")
+	expected := []string{
+		`script.txt:2:1: requested number is in invalid format`,
+		`script.txt:4:10: i have no idea what are you talking about: unexpected "wood", expected "is"`,
+		`script.txt:5:3: requested number is in invalid format`,
+		`script.txt:6:21: credits is not a number: unexpected "lots", expected a number`,
+	}
+
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.SingleWorkspace(&MockDatabase{isError: true}, &MockCalculator{isError: true}), "script.txt", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+func TestRunIntergalacticConverterLocatesUnknownNames(t *testing.T) {
+	input := bytes.NewBufferString("glob is I\nhow much is glob blah ?\nhow many credits is glob Gold ?\n")
+	expected := []string{
+		`script.txt:2:18: blah unit is not defined in the intergalactic database`,
+		`script.txt:3:26: gold currency is not defined in the intergalactic database`,
+	}
+
+	db := database.NewDatabase()
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "script.txt", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+func TestRunIntergalacticConverterNumeralSystems(t *testing.T) {
+	input := bytes.NewBufferString(strings.Join([]string{
+		"hun is 1",
+		"kal is 0",
+		"deka is Δ",
+		"pente is Π",
+		"hun kal Jade is 40 Credits",
+		"how much is hun kal hun ?",
+		"how many Credits is hun hun Jade ?",
+		"how many Credits is kal Jade ?",
+		"how much is deka pente ?",
+		"how do you say 21 in mayan ?",
+		"how do you say 20 in attic ?",
+		"how much is hun deka ?",
+		"zorg is Z",
+		"glob is I",
+		"mek is _I",
+		"vek is V̅",
+		"how much is mek vek glob ?",
+		"how do you say 4001 ?",
+	}, "\n"))
+	expected := []string{
+		"hun kal hun is 401",
+		"hun hun jade is 42.00 Credits",
+		"kal can not be computed, a quantity of a currency has to be positive",
+		"deka pente is 15",
+		"21 is hun hun",
+		"20 is deka deka",
+		"requested number is in invalid format: 1Δ is invalid at symbol 2, Δ is not a mayan symbol",
+		"requested number is in invalid format: Z is invalid at symbol 1, Z is not a numeral symbol",
+		"mek vek glob is 4001",
+		"4001 is mek vek glob",
+	}
+
+	db := database.NewDatabase()
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+func TestRunIntergalacticConverterRedefinesAndForgets(t *testing.T) {
+	input := bytes.NewBufferString(strings.Join([]string{
+		"glob is I",
+		"prok is V",
+		"glob is X",
+		"pish is V",
+		"glob Silver is 17 Credits",
+		"glob Silver is 17 Credits",
+		"glob Silver is 20 Credits",
+		"list units",
+		"list currencies",
+		"forget pish",
+		"forget silver",
+		"forget silver",
+		"list currencies",
+		"list units",
+	}, "\n"))
+	expected := []string{
+		"warning: glob unit is already assigned to I",
+		"warning: V symbol is already assigned to prok",
+		"warning: silver currency is already worth 1.70 Credits",
+		"glob is X",
+		"pish is V",
+		"prok is V",
+		"1 silver is 2.00 Credits",
+		"silver is neither a unit nor a currency in the intergalactic database",
+		"no currencies defined",
+		"glob is X",
+		"prok is V",
+	}
+
+	db := database.NewDatabase()
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+func TestForgetCurrencyTwice(t *testing.T) {
+	db := database.NewDatabase()
+	calc := calculator.NewCalculator(db)
+	db.DefineUnit("glob", "I", calc.RateFromSymbols)
+	db.DefineRate("silver", database.RateSource{Units: []string{"glob"}, Credits: big.NewRat(17, 1)}, calc.RateFromSymbols)
+
+	if _, err := forget(db, calc, "Silver"); err != nil {
+		t.Fatalf("forget() error = %v", err)
+	}
+	_, err := forget(db, calc, "Silver")
+	var unknown *constant.UnknownNameError
+	if !errors.As(err, &unknown) || unknown.Name != "silver" || !errors.Is(err, constant.ErrNotDefined) {
+		t.Errorf("forget() of a forgotten currency error = %v, want a *UnknownNameError for silver", err)
+	}
+}
+
+func TestRunIntergalacticConverterFollowsUnitChanges(t *testing.T) {
+	input := bytes.NewBufferString(strings.Join([]string{
+		"glob is I",
+		"prok is V",
+		"glob glob Silver is 34 Credits",
+		"glob prok Gold is 57800 Credits",
+		"glob is X",
+		"how many Credits is prok Silver ?",
+		"forget prok",
+		"how many Credits is glob Gold ?",
+		"how many Silver is glob Gold ?",
+		"prok is L",
+		"how many Credits is glob Gold ?",
+	}, "\n"))
+	expected := []string{
+		"warning: glob unit is already assigned to I",
+		"prok silver is 8.50 Credits",
+		"glob gold is 38533.33 Credits (stale rate for gold)",
+		"glob gold is 22666.666667 silver (stale rate for gold)",
+		"glob gold is 14450.00 Credits",
+	}
+
+	db := database.NewDatabase()
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+func TestRunIntergalacticConverterTransactions(t *testing.T) {
+	input := bytes.NewBufferString(strings.Join([]string{
+		"glob is I",
+		"prok is V",
+		"begin",
+		"pish is X",
+		"glob glob Silver is 34 Credits",
+		"tegj Gold is 100 Credits",
+		"pish Iron is 3910 Credits",
+		"commit",
+		"how much is pish ?",
+		"how many Credits is glob Silver ?",
+		"begin",
+		"pish is X",
+		"pish Iron is 3910 Credits",
+		"commit",
+		"how many Credits is pish Iron ?",
+		"undo",
+		"how much is pish ?",
+		"redo",
+		"how much is pish ?",
+		"undo",
+		"undo",
+		"undo",
+		"undo",
+		"list units",
+		"rollback",
+		"begin",
+		"pish is X",
+	}, "\n"))
+	expected := []string{
+		"tegj unit is not defined in the intergalactic database",
+		"transaction rolled back after an error",
+		"pish unit is not defined in the intergalactic database",
+		"silver currency is not defined in the intergalactic database",
+		"pish iron is 3910.00 Credits",
+		"pish unit is not defined in the intergalactic database",
+		"pish is 10",
+		"there is nothing to undo",
+		"no units defined",
+		"no transaction is in progress",
+		"transaction rolled back, it was never committed",
+	}
+
+	db := database.NewDatabase()
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+	if units := db.ListUnits(); len(units) != 0 {
+		t.Errorf("Expected the unfinished block to be rolled back, got %v", units)
+	}
+}
+
+func TestRunIntergalacticConverterNamespaces(t *testing.T) {
+	input := bytes.NewBufferString(strings.Join([]string{
+		"glob is I",
+		"glob glob Silver is 34 Credits",
+		"use namespace team-b",
+		"glob is X",
+		"how much is glob glob ?",
+		"how many Credits is glob Silver ?",
+		"begin",
+		"use namespace default",
+		"rollback",
+		"undo",
+		"use namespace default",
+		"how much is glob glob ?",
+		"undo",
+		"how much is glob glob ?",
+		"use namespace team-b",
+		"how much is glob ?",
+		"use namespace team b",
+		"use namespace ../etc",
+	}, "\n"))
+	expected := []string{
+		"glob glob is 20",
+		"silver currency is not defined in the intergalactic database",
+		"a transaction is already in progress",
+		"glob glob is 2",
+		"glob glob is 2",
+		"glob unit is not defined in the intergalactic database",
+		`i have no idea what are you talking about: unexpected "b", expected end of line`,
+		`"../etc" is not a valid namespace, use up to 32 letters, digits, - and _`,
+	}
+
+	namespaces := database.NewNamespaces(func(name string) (database.Database, error) {
+		return database.NewDatabase(), nil
+	}, nil)
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.NewWorkspace(namespaces, database.DefaultNamespace), "", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+func TestRunIntergalacticConverterUnknownNamespace(t *testing.T) {
+	db := database.NewDatabase()
+	input := bytes.NewBufferString("use namespace team-a")
+	var output bytes.Buffer
+
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "script.txt", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	expected := []string{"script.txt:1:15: team-a namespace is not defined in the intergalactic database"}
+	if got := outputLines(output.String()); !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+// dailyClock moves a day forward every time it is read
+type dailyClock struct {
+	now time.Time
+}
+
+func (c *dailyClock) Now() time.Time {
+	now := c.now
+	c.now = c.now.AddDate(0, 0, 1)
+	return now
+}
+
+func TestRunIntergalacticConverterKeepsRateHistory(t *testing.T) {
+	input := bytes.NewBufferString(strings.Join([]string{
+		"glob is I",
+		"prok is V",
+		"glob prok Gold is 57800 Credits",
+		"glob prok Gold is 60000 Credits",
+		"how many Credits was glob prok Gold at 2026-01-01 ?",
+		"how many Credits was glob prok Gold at 2026-01-02 ?",
+		"how many Credits was glob prok Gold at 2025-12-31 ?",
+		"how many Credits was glob prok Gold at yesterday ?",
+		"how many Credits is glob prok Gold ?",
+		"list history of Gold",
+		"list history of Silver",
+	}, "\n"))
+	expected := []string{
+		"warning: gold currency is already worth 14450.00 Credits",
+		"glob prok gold was 57800.00 Credits on 2026-01-01",
+		"glob prok gold was 60000.00 Credits on 2026-01-02",
+		"gold currency has no rate yet on 2025-12-31",
+		`i have no idea what are you talking about: unexpected "yesterday", expected a date as YYYY-MM-DD`,
+		"glob prok gold is 60000.00 Credits",
+		"1 gold is 14450.00 Credits since 2026-01-01T00:00:00Z",
+		"1 gold is 15000.00 Credits since 2026-01-02T00:00:00Z",
+		"silver currency is not defined in the intergalactic database",
+	}
+
+	clock := &dailyClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	db := database.NewDatabase(database.WithClock(clock))
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+func TestRunIntergalacticConverterConvertsCurrencies(t *testing.T) {
+	input := bytes.NewBufferString(strings.Join([]string{
+		"glob is I",
+		"prok is V",
+		"pish is X",
+		"tegj is L",
+		"glob glob Silver is 34 Credits",
+		"glob prok Gold is 57800 Credits",
+		"how many Silver is glob Gold ?",
+		"how many Gold is 10 Silver ?",
+		"how many Silver is pish pish Silver ?",
+		"how many Silver is 3000 Gold ?",
+		"how many Iron is glob Gold ?",
+		"glob Platinum is 10000000 Credits",
+		"how many Platinum is glob glob Silver ?",
+	}, "\n"))
+	expected := []string{
+		"glob gold is 850 silver",
+		"10 silver is 0.011765 gold",
+		"pish pish silver is 20 silver (pish pish silver)",
+		"3000 gold is 2550000 silver",
+		"iron currency is not defined in the intergalactic database",
+		"glob glob silver is 0.0000034 platinum",
+	}
+
+	db := database.NewDatabase()
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+func TestRunIntergalacticConverterEvaluatesExpressions(t *testing.T) {
+	input := bytes.NewBufferString(strings.Join([]string{
+		"glob is I",
+		"prok is V",
+		"pish is X",
+		"tegj is L",
+		"glob glob Silver is 34 Credits",
+		"how much is pish tegj plus glob glob ?",
+		"how much is (pish plus glob) times prok ?",
+		"how much is glob minus prok ?",
+		"how much is tegj times tegj ?",
+		"how much is pish divided by (prok minus glob glob) ?",
+		"how much is pish divided by (glob minus glob) ?",
+		"how much is (glob plus wood) ?",
+		"how many Credits is (glob prok plus pish) Silver ?",
+		"how many Credits is glob times prok Silver ?",
+		"how many Credits is glob minus prok Silver ?",
+		"how many Credits is (glob minus glob) Silver ?",
+		"plus is V",
+		"how much is glob plus ?",
+	}, "\n"))
+	expected := []string{
+		"pish tegj plus glob glob is 42 (pish tegj glob glob)",
+		"(pish plus glob) times prok is 55 (tegj prok)",
+		"glob minus prok is -4",
+		"tegj times tegj is 2500",
+		"10 divided by 3 can not be computed, the result is not a whole number",
+		"10 divided by 0 can not be computed, a number can not be divided by zero",
+		"wood unit is not defined in the intergalactic database",
+		"(glob prok plus pish) silver is 238.00 Credits",
+		"(glob times prok) silver is 85.00 Credits",
+		"glob minus prok can not be computed, a quantity of a currency has to be positive",
+		"glob minus glob can not be computed, a quantity of a currency has to be positive",
+		"warning: V symbol is already assigned to prok",
+		"glob plus is 4",
+	}
+
+	db := database.NewDatabase()
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+func TestRunIntergalacticConverterAnswersPurchases(t *testing.T) {
+	input := bytes.NewBufferString(strings.Join([]string{
+		"glob is I",
+		"prok is V",
+		"pish is X",
+		"glob glob Silver is 34 Credits",
+		"glob prok Iron is 782 Credits",
+		"how many Silver can I buy with 500 Credits ?",
+		"how many silver can i buy with 10 credits",
+		"how many Iron can I buy with 1000 Credits ?",
+		"how many Silver can I buy with 100000 Credits ?",
+		"how many Gold can I buy with 5 Credits ?",
+	}, "\n"))
+	expected := []string{
+		"500 Credits buy 29 silver (pish pish glob pish silver), 7.00 Credits left",
+		"10 Credits buy 0 silver, 10.00 Credits left",
+		"1000 Credits buy 5 iron (prok iron), 22.50 Credits left",
+		"100000 Credits buy 5882 silver, 6.00 Credits left",
+		"gold currency is not defined in the intergalactic database",
+	}
+
+	db := database.NewDatabase()
+	var output bytes.Buffer
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "", input, &output, sessionOptions{})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	got := outputLines(output.String())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+}
+
+func TestFormatQuantity(t *testing.T) {
+	tests := []struct {
+		quantity *big.Rat
+		expected string
+	}{
+		{big.NewRat(42, 1), "42"},
+		{big.NewRat(21, 2), "10.5"},
+		{big.NewRat(1, 85), "0.011765"},
+		{big.NewRat(1, 10000000), "0.0000001"},
+		{big.NewRat(1, 30000000), "0.000000033333"},
+		{big.NewRat(34, 10000000), "0.0000034"},
+		{big.NewRat(-1, 10000000), "-0.0000001"},
+	}
+
+	for _, tt := range tests {
+		if result := formatQuantity(tt.quantity); result != tt.expected {
+			t.Errorf("formatQuantity(%s) = %s, want %s", tt.quantity.RatString(), result, tt.expected)
+		}
+	}
+}
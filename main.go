@@ -1,18 +1,138 @@
 package main
 
 import (
-	"fmt"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/term"
+
 	"github.com/erizkiatama/prospace-assignment/calculator"
 	"github.com/erizkiatama/prospace-assignment/database"
-	"os"
+	"github.com/erizkiatama/prospace-assignment/server"
 )
 
+// stdinSource names piped input in the location of its errors
+const stdinSource = "<stdin>"
+
 func main() {
-	db := database.NewDatabase()
-	calc := calculator.NewCalculator(db)
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+
+	dbPath := flag.String("db", "", "JSON file to load and save units and currencies, keeps everything in memory when empty")
+	historyPath := flag.String("history", defaultHistoryPath(), "file keeping the interactive history, none when empty")
+	lenient := flag.Bool("lenient", false, "accept historical roman numerals such as IIII and IIX")
+	redefine := flag.String("redefine", "warn", "what to do when a definition changes an existing one: warn, reject or overwrite")
+	namespace := flag.String("namespace", database.DefaultNamespace, "namespace whose units and currencies are used until `use namespace` switches")
+	input := flag.String("input", "auto", "how statements are written: text, json or yaml documents, or auto to detect it")
+	output := flag.String("output", "text", "how answers are written: text, or json for a JSON object per statement")
+	explain := flag.Bool("explain", false, "show how every answer is derived, step by step")
+	flag.Parse()
+
+	options := sessionOptions{explain: *explain}
+	var err error
+	if options.input, err = parseInputFormat(*input); err != nil {
+		log.Fatal(err)
+	}
+	if options.output, err = parseOutputFormat(*output); err != nil {
+		log.Fatal(err)
+	}
+	workspace := openWorkspace(*dbPath, *redefine, *namespace, *lenient)
+
+	// A terminal gets the interactive REPL, piped input is answered in batch
+	if flag.NArg() == 0 && term.IsTerminal(int(os.Stdin.Fd())) {
+		if err := runREPL(workspace, *historyPath, options); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Script files are run in order on the same database, errors in them are
+	// located by file, line and column. Piped input is a script named <stdin>.
+	if flag.NArg() == 0 {
+		if err := runIntergalacticConverter(workspace, stdinSource, os.Stdin, os.Stdout, options); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	for _, path := range flag.Args() {
+		if err := runScript(workspace, path, options); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".intergalactic_history")
+}
+
+func runScript(workspace calculator.Workspace, path string, options sessionOptions) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return runIntergalacticConverter(workspace, path, file, os.Stdout, options)
+}
+
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	dbPath := flags.String("db", "", "JSON file to load and save units and currencies, keeps everything in memory when empty")
+	lenient := flags.Bool("lenient", false, "accept historical roman numerals such as IIII and IIX")
+	redefine := flags.String("redefine", "warn", "what to do when a definition changes an existing one: warn, reject or overwrite")
+	namespace := flags.String("namespace", database.DefaultNamespace, "namespace used by requests without an X-Namespace header")
+	flags.Parse(args)
+
+	workspace := openWorkspace(*dbPath, *redefine, *namespace, *lenient)
+
+	log.Printf("intergalactic converter listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server.NewServer(workspace)))
+}
+
+func calculatorOptions(lenient bool) []calculator.Option {
+	if lenient {
+		return []calculator.Option{calculator.WithLenientNumerals()}
+	}
+	return nil
+}
+
+// openWorkspace opens the database of each namespace on first use. With a
+// path, every namespace but the default one is kept in its own file next to it.
+func openWorkspace(path, redefine, namespace string, lenient bool) calculator.Workspace {
+	policy, err := database.ParsePolicy(redefine)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	namespaces := database.NewNamespaces(func(name string) (database.Database, error) {
+		if path == "" {
+			return database.NewDatabase(database.WithPolicy(policy)), nil
+		}
+		return database.NewFileDatabase(database.NamespacePath(path, name), database.WithPolicy(policy))
+	}, func(name string) bool {
+		// A namespace kept in memory only exists once it is opened
+		if path == "" {
+			return false
+		}
+		_, err := os.Stat(database.NamespacePath(path, name))
+		return err == nil
+	})
+	workspace := calculator.NewWorkspace(namespaces, namespace, calculatorOptions(lenient)...)
 
-	responses := runIntergalacticConverter(db, calc, os.Stdin)
-	for _, response := range responses {
-		fmt.Println(response)
+	// The namespace used by default is opened right away, so a bad file or
+	// name is reported before any input is read
+	if _, _, err := workspace("", true); err != nil {
+		log.Fatal(err)
 	}
+	return workspace
 }
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+	"github.com/erizkiatama/prospace-assignment/parser"
+)
+
+// outputFormat is how the answers of a session are written
+type outputFormat int
+
+const (
+	// textOutput writes the answers as English sentences
+	textOutput outputFormat = iota
+	// jsonOutput writes a JSON object per statement, one per line
+	jsonOutput
+)
+
+// parseOutputFormat reads an output format from its name, text or json
+func parseOutputFormat(name string) (outputFormat, error) {
+	switch strings.ToLower(name) {
+	case "text":
+		return textOutput, nil
+	case "json":
+		return jsonOutput, nil
+	}
+	return 0, fmt.Errorf("unknown output format %q, use text or json", name)
+}
+
+// answer is what a statement results in. Text is the English answer, the
+// other fields are for machines.
+type answer struct {
+	Text   string `json:"text,omitempty"`
+	Result any    `json:"result,omitempty"`
+	// Roman is the numeral each run of units was read as, e.g. XLII
+	Roman   []string `json:"roman,omitempty"`
+	Warning string   `json:"warning,omitempty"`
+	// Stale lists the currencies whose rate could not follow a change of its units
+	Stale []string `json:"stale,omitempty"`
+	// Explanation is the derivation of the answer, step by step
+	Explanation []string `json:"explanation,omitempty"`
+	// basis is what the answer was computed from, for its explanation
+	basis basis
+}
+
+// record is written for every statement, as its text answer or as a JSON object
+type record struct {
+	Source   string           `json:"source,omitempty"`
+	Line     int              `json:"line"`
+	Type     string           `json:"type"`
+	Operands parser.Statement `json:"operands,omitempty"`
+	answer
+	// Skipped is set on the statements of a block after one of them failed
+	Skipped bool         `json:"skipped,omitempty"`
+	Error   *recordError `json:"error,omitempty"`
+
+	err    error
+	column int
+}
+
+type recordError struct {
+	Code    constant.Code `json:"code"`
+	Message string        `json:"message"`
+	Column  int           `json:"column"`
+}
+
+func newRecord(stmt parser.Statement) record {
+	return record{Type: statementType(stmt), Operands: stmt}
+}
+
+// failed records the error of the statement, at the column it is about
+func (r record) failed(column int, err error) record {
+	r.err, r.column = err, column
+	return r
+}
+
+// Result types, the credits are written with 2 digits after point like in the
+// text answers, and the rates as exact rationals
+
+type unitResult struct {
+	Unit   string `json:"unit"`
+	Symbol string `json:"symbol"`
+}
+
+type rateResult struct {
+	Currency string `json:"currency"`
+	Rate     string `json:"rate"`
+	Stale    bool   `json:"stale,omitempty"`
+}
+
+type ratePointResult struct {
+	Since string `json:"since,omitempty"`
+	Rate  string `json:"rate"`
+}
+
+type valueResult struct {
+	Value int `json:"value"`
+	// Units write the value of an expression back, when they can
+	Units []string `json:"units,omitempty"`
+}
+
+type creditsResult struct {
+	Credits string `json:"credits"`
+}
+
+type conversionResult struct {
+	Amount   string   `json:"amount"`
+	Currency string   `json:"currency"`
+	Units    []string `json:"units,omitempty"`
+}
+
+type purchaseResult struct {
+	Quantity  int      `json:"quantity"`
+	Units     []string `json:"units,omitempty"`
+	Currency  string   `json:"currency"`
+	Remainder string   `json:"remainder"`
+}
+
+type unitsResult struct {
+	Units []string `json:"units"`
+}
+
+type comparisonResult struct {
+	Comparison string `json:"comparison"`
+}
+
+// statementType names a statement in snake case, unknown for a line that
+// could not be parsed
+func statementType(stmt parser.Statement) string {
+	switch stmt.(type) {
+	case parser.UnitDefinition:
+		return "unit_definition"
+	case parser.RateDefinition:
+		return "rate_definition"
+	case parser.NumeralQuery:
+		return "numeral_query"
+	case parser.CreditQuery:
+		return "credit_query"
+	case parser.ConversionQuery:
+		return "conversion_query"
+	case parser.PurchaseQuery:
+		return "purchase_query"
+	case parser.TranslationQuery:
+		return "translation_query"
+	case parser.UnitComparison:
+		return "unit_comparison"
+	case parser.CreditComparison:
+		return "credit_comparison"
+	case parser.Removal:
+		return "removal"
+	case parser.Listing:
+		return "listing"
+	case parser.Control:
+		return "control"
+	case parser.NamespaceUse:
+		return "namespace_use"
+	default:
+		return "unknown"
+	}
+}
+
+// emit writes the record of a statement in the output format of the session.
+// The text output only has a line for answers and errors, the JSON output has
+// one for every statement.
+func (s *session) emit(r record) {
+	r.Source, r.Line = s.source, s.line
+
+	if s.output == jsonOutput {
+		if r.err != nil {
+			r.Error = &recordError{Code: constant.CodeOf(r.err), Message: r.err.Error(), Column: r.column}
+		}
+		data, err := json.Marshal(r)
+		if err != nil {
+			data, _ = json.Marshal(record{Source: r.Source, Line: r.Line, Type: r.Type, Error: &recordError{
+				Code: constant.CodeInternal, Message: err.Error(), Column: r.column,
+			}})
+		}
+		fmt.Fprintln(s.writer, string(data))
+		return
+	}
+
+	switch {
+	case r.err != nil:
+		err := r.err
+		if s.source != "" {
+			err = &scriptError{source: s.source, line: s.line, column: r.column, err: err}
+		}
+		fmt.Fprintln(s.writer, err.Error())
+	case r.Text != "":
+		fmt.Fprintln(s.writer, r.Text)
+	}
+	if r.err == nil {
+		for _, step := range r.Explanation {
+			fmt.Fprintln(s.writer, "  "+step)
+		}
+	}
+}
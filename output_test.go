@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/calculator"
+	"github.com/erizkiatama/prospace-assignment/database"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected outputFormat
+		isError  bool
+	}{
+		{"text", textOutput, false},
+		{"JSON", jsonOutput, false},
+		{"xml", 0, true},
+	}
+
+	for _, tt := range tests {
+		format, err := parseOutputFormat(tt.name)
+		if (err != nil) != tt.isError {
+			t.Errorf("parseOutputFormat(%s) error = %v, wantErr %v", tt.name, err, tt.isError)
+		}
+		if format != tt.expected {
+			t.Errorf("parseOutputFormat(%s) = %v, want %v", tt.name, format, tt.expected)
+		}
+	}
+}
+
+func TestRunIntergalacticConverterJSONOutput(t *testing.T) {
+	db := database.NewDatabase()
+	input := bytes.NewBufferString(`glob is I
+prok is V
+pish is X
+# comments have no record
+glob glob Silver is 34 Credits
+glob is V
+glob is I
+how much is pish glob ?
+how many Credits is glob prok Silver ?
+how many Credits is 3 Silver ?
+how many Silver is 12 Silver ?
+is glob larger than prok ?
+list units
+how much is wood ?
+pish pash
+forget glob
+does prok Silver has more Credits than pish Silver ?
+begin
+how much is zz ?
+how much is prok ?
+commit
+help`)
+	var output bytes.Buffer
+
+	err := runIntergalacticConverter(calculator.SingleWorkspace(db, calculator.NewCalculator(db)), "script.txt", input, &output, sessionOptions{output: jsonOutput})
+	if err != nil {
+		t.Fatalf("runIntergalacticConverter() error = %v", err)
+	}
+
+	expected := []string{
+		`{"source":"script.txt","line":1,"type":"unit_definition","operands":{"unit":"glob","symbol":"i"},"result":{"unit":"glob","symbol":"I"}}`,
+		`{"source":"script.txt","line":2,"type":"unit_definition","operands":{"unit":"prok","symbol":"v"},"result":{"unit":"prok","symbol":"V"}}`,
+		`{"source":"script.txt","line":3,"type":"unit_definition","operands":{"unit":"pish","symbol":"x"},"result":{"unit":"pish","symbol":"X"}}`,
+		`{"source":"script.txt","line":5,"type":"rate_definition","operands":{"units":["glob","glob"],"currency":"silver","credits":"34"},"result":{"currency":"silver","rate":"17"},"roman":["II"]}`,
+		`{"source":"script.txt","line":6,"type":"unit_definition","operands":{"unit":"glob","symbol":"v"},"text":"warning: glob unit is already assigned to I","result":{"unit":"glob","symbol":"V"},"warning":"glob unit is already assigned to I","stale":["silver"]}`,
+		`{"source":"script.txt","line":7,"type":"unit_definition","operands":{"unit":"glob","symbol":"i"},"text":"warning: glob unit is already assigned to V","result":{"unit":"glob","symbol":"I"},"warning":"glob unit is already assigned to V"}`,
+		`{"source":"script.txt","line":8,"type":"numeral_query","operands":{"units":["pish","glob"]},"text":"pish glob is 11","result":{"value":11},"roman":["XI"]}`,
+		`{"source":"script.txt","line":9,"type":"credit_query","operands":{"units":["glob","prok"],"currency":"silver"},"text":"glob prok silver is 68.00 Credits","result":{"credits":"68.00"},"roman":["IV"]}`,
+		`{"source":"script.txt","line":10,"type":"conversion_query","operands":{"target":"credits","amount":"3","currency":"silver"},"text":"3 silver is 51.00 Credits","result":{"credits":"51.00"}}`,
+		`{"source":"script.txt","line":11,"type":"conversion_query","operands":{"target":"silver","amount":"12","currency":"silver"},"text":"12 silver is 12 silver (pish glob glob silver)","result":{"amount":"12","currency":"silver","units":["pish","glob","glob"]},"roman":["XII"]}`,
+		`{"source":"script.txt","line":12,"type":"unit_comparison","operands":{"first":["glob"],"second":["prok"]},"text":"glob is smaller than prok","result":{"comparison":"smaller than"},"roman":["I","V"]}`,
+		`{"source":"script.txt","line":13,"type":"listing","operands":{"kind":"units"},"text":"glob is I\npish is X\nprok is V","result":[{"unit":"glob","symbol":"I"},{"unit":"pish","symbol":"X"},{"unit":"prok","symbol":"V"}]}`,
+		`{"source":"script.txt","line":14,"type":"numeral_query","operands":{"units":["wood"]},"error":{"code":"unknown_unit","message":"wood unit is not defined in the intergalactic database","column":13}}`,
+		`{"source":"script.txt","line":15,"type":"unknown","error":{"code":"parse_failure","message":"i have no idea what are you talking about: unexpected \"pash\", expected \"is\"","column":6}}`,
+		`{"source":"script.txt","line":16,"type":"removal","operands":{"name":"glob"},"stale":["silver"]}`,
+		`{"source":"script.txt","line":17,"type":"credit_comparison","operands":{"first":{"units":["prok"],"currency":"silver"},"second":{"units":["pish"],"currency":"silver"}},"text":"prok silver has less credits than pish silver (stale rate for silver)","result":{"comparison":"has less credits than"},"roman":["V","X"],"stale":["silver"]}`,
+		`{"source":"script.txt","line":18,"type":"control","operands":{"command":"begin"}}`,
+		`{"source":"script.txt","line":19,"type":"numeral_query","operands":{"units":["zz"]},"error":{"code":"unknown_unit","message":"zz unit is not defined in the intergalactic database","column":13}}`,
+		`{"source":"script.txt","line":20,"type":"numeral_query","operands":{"units":["prok"]},"skipped":true}`,
+		`{"source":"script.txt","line":21,"type":"control","operands":{"command":"commit"},"text":"transaction rolled back after an error"}`,
+	}
+
+	got := outputLines(output.String())
+	// The help text spans several lines, only its record start is checked
+	if len(got) < len(expected) || !reflect.DeepEqual(got[:len(expected)], expected) {
+		t.Fatalf("runIntergalacticConverter() = %v, want %v", got, expected)
+	}
+	if help := got[len(expected)]; !bytes.HasPrefix([]byte(help), []byte(`{"source":"script.txt","line":22,"type":"help","text":"Supported sentences`)) {
+		t.Errorf("runIntergalacticConverter() help = %s", help)
+	}
+}
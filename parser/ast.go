@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Statement is a single parsed input line
+type Statement interface {
+	statementNode()
+}
+
+// UnitDefinition assigns a numeral symbol to a unit, `{unit} is {symbol}`
+type UnitDefinition struct {
+	Unit   string `json:"unit"`
+	Symbol string `json:"symbol"`
+}
+
+// RateDefinition sets the credits of a currency,
+// `{units} {currency} is {credits} Credits`
+type RateDefinition struct {
+	Units    []string `json:"units"`
+	Currency string   `json:"currency"`
+	Credits  *big.Rat `json:"credits"`
+}
+
+// NumeralQuery asks for the value of units, `how much is {units} ?`.
+// Expression is set instead of Units when the value is computed, e.g.
+// `how much is pish tegj plus glob glob ?`.
+type NumeralQuery struct {
+	Units      []string   `json:"units,omitempty"`
+	Expression Expression `json:"expression,omitempty"`
+}
+
+// CreditQuery asks for the credits of a quantity of currency,
+// `how many Credits is {units} {currency} ?`, or for the credits it was worth
+// on a day, `how many Credits was {units} {currency} at {date} ?`. A nil At
+// asks for the current rate. Expression is set instead of Units when the
+// quantity is computed, e.g. `how many Credits is (glob prok plus pish) Silver ?`.
+type CreditQuery struct {
+	Units      []string   `json:"units,omitempty"`
+	Expression Expression `json:"expression,omitempty"`
+	Currency   string     `json:"currency"`
+	At         *time.Time `json:"at,omitempty"`
+}
+
+// ConversionQuery asks how much of the target currency is worth a quantity of
+// another currency, `how many {currency} is {units|number} {currency} ?`.
+// Amount is set instead of Units when the quantity is written as a number.
+type ConversionQuery struct {
+	Target   string   `json:"target"`
+	Units    []string `json:"units,omitempty"`
+	Amount   *big.Rat `json:"amount,omitempty"`
+	Currency string   `json:"currency"`
+}
+
+// PurchaseQuery asks how much of a currency a budget of credits buys,
+// `how many {currency} can I buy with {number} Credits ?`
+type PurchaseQuery struct {
+	Currency string   `json:"currency"`
+	Credits  *big.Rat `json:"credits"`
+}
+
+// TranslationQuery asks for the units of a number in a numeral system,
+// `how do you say {number} [in {system}] ?`. An empty System is Roman.
+type TranslationQuery struct {
+	Number int    `json:"number"`
+	System string `json:"system,omitempty"`
+}
+
+// UnitComparison compares the value of two units,
+// `is {units} larger|smaller than {units} ?`
+type UnitComparison struct {
+	First  []string `json:"first"`
+	Second []string `json:"second"`
+}
+
+// Quantity is an amount of a currency written in units
+type Quantity struct {
+	Units    []string `json:"units"`
+	Currency string   `json:"currency"`
+}
+
+// CreditComparison compares the credits of two quantities,
+// `does {units} {currency} has more|less Credits than {units} {currency} ?`
+type CreditComparison struct {
+	First  Quantity `json:"first"`
+	Second Quantity `json:"second"`
+}
+
+// Removal forgets a unit, a currency or both when they share the name,
+// `forget {name}`
+type Removal struct {
+	Name string `json:"name"`
+}
+
+// Listing asks for every defined unit or currency, `list units|currencies`,
+// or for every rate a currency had, `list history of {currency}`. Kind is
+// units, currencies or history.
+type Listing struct {
+	Kind     string `json:"kind"`
+	Currency string `json:"currency,omitempty"`
+}
+
+// Control groups changes in a block, or takes them back, `begin`, `commit`,
+// `rollback`, `undo` or `redo`. Command is one of them.
+type Control struct {
+	Command string `json:"command"`
+}
+
+// NamespaceUse switches to the units and currencies of another namespace,
+// `use namespace {name}`
+type NamespaceUse struct {
+	Name string `json:"name"`
+}
+
+// Expression is arithmetic over the values of units
+type Expression interface {
+	expressionNode()
+	// String writes the expression back as it is read, with the parentheses
+	// it needs only
+	String() string
+}
+
+// The operators of an expression, written as they are read
+const (
+	Plus    = "plus"
+	Minus   = "minus"
+	Times   = "times"
+	Divided = "divided by"
+)
+
+// Operand is the value of a run of units
+type Operand struct {
+	Units []string `json:"units"`
+}
+
+// Operation applies an operator to the values of two expressions
+type Operation struct {
+	Operator string     `json:"operator"`
+	Left     Expression `json:"left"`
+	Right    Expression `json:"right"`
+}
+
+func (Operand) expressionNode()   {}
+func (Operation) expressionNode() {}
+
+func (o Operand) String() string {
+	return strings.Join(o.Units, " ")
+}
+
+func (o Operation) String() string {
+	left, right := o.Left.String(), o.Right.String()
+	// Operators of the same precedence are read from the left, so only a
+	// right operand needs parentheses to keep its place
+	if operation, ok := o.Left.(Operation); ok && precedence(operation.Operator) < precedence(o.Operator) {
+		left = "(" + left + ")"
+	}
+	if operation, ok := o.Right.(Operation); ok && precedence(operation.Operator) <= precedence(o.Operator) {
+		right = "(" + right + ")"
+	}
+	return left + " " + o.Operator + " " + right
+}
+
+// Operands returns the operands of an expression from left to right
+func Operands(expr Expression) []Operand {
+	switch expr := expr.(type) {
+	case Operand:
+		return []Operand{expr}
+	case Operation:
+		return append(Operands(expr.Left), Operands(expr.Right)...)
+	}
+	return nil
+}
+
+func precedence(operator string) int {
+	if operator == Times || operator == Divided {
+		return 2
+	}
+	return 1
+}
+
+func (UnitDefinition) statementNode()   {}
+func (RateDefinition) statementNode()   {}
+func (NumeralQuery) statementNode()     {}
+func (CreditQuery) statementNode()      {}
+func (ConversionQuery) statementNode()  {}
+func (PurchaseQuery) statementNode()    {}
+func (TranslationQuery) statementNode() {}
+func (UnitComparison) statementNode()   {}
+func (CreditComparison) statementNode() {}
+func (Removal) statementNode()          {}
+func (Listing) statementNode()          {}
+func (Control) statementNode()          {}
+func (NamespaceUse) statementNode()     {}
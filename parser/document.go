@@ -0,0 +1,538 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+// A document is a statement written as a JSON object, or a YAML mapping, for
+// programs generating statements. The op field names the statement and the
+// other fields are its operands, with the same rules as in sentences:
+//
+//	define_unit      unit, roman
+//	define_rate      units, currency, credits
+//	query_value      units
+//	query_credits    units, currency, [at]
+//	convert          target, (units | amount), currency
+//	query_purchase   currency, credits
+//	translate        number, [system]
+//	compare_units    first, second
+//	compare_credits  first, second, each a {units, currency} object
+//	forget           name
+//	list             kind, [currency] with the history kind
+//	begin | commit | rollback | undo | redo
+//	use_namespace    name
+//
+// Units are a list of words, credits and amounts are numbers and at is a day
+// written as YYYY-MM-DD.
+var operations = map[string]func(*document) (Statement, error){
+	"define_unit":     (*document).unitDefinition,
+	"define_rate":     (*document).rateDefinition,
+	"query_value":     (*document).numeralQuery,
+	"query_credits":   (*document).creditQuery,
+	"convert":         (*document).conversionQuery,
+	"query_purchase":  (*document).purchaseQuery,
+	"translate":       (*document).translationQuery,
+	"compare_units":   (*document).unitComparison,
+	"compare_credits": (*document).creditComparison,
+	"forget":          (*document).removal,
+	"list":            (*document).listing,
+	"begin":           (*document).control,
+	"commit":          (*document).control,
+	"rollback":        (*document).control,
+	"undo":            (*document).control,
+	"redo":            (*document).control,
+	"use_namespace":   (*document).namespaceUse,
+}
+
+// FieldError tells which field of a document is wrong and why. It unwraps to
+// one of the errors in constant, constant.ErrInvalidDocument for most fields.
+type FieldError struct {
+	// Field is the path of the field, e.g. units[1] or first.currency
+	Field    string
+	Expected string
+	// Found is the value written, empty when the field is missing
+	Found string
+	Err   error
+	// Line and Column are where the field is written in a YAML document,
+	// counting from 1, 0 when it is not known
+	Line   int
+	Column int
+}
+
+func (e *FieldError) Error() string {
+	if e.Expected == "" {
+		return fmt.Sprintf("%s: unknown field %q", e.Err, e.Field)
+	}
+
+	found := "nothing"
+	if e.Found != "" {
+		found = e.Found
+	}
+	return fmt.Sprintf("%s: field %q: expected %s, found %s", e.Err, e.Field, e.Expected, found)
+}
+
+// Code is the code of the wrapped error
+func (e *FieldError) Code() constant.Code {
+	return constant.CodeOf(e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// IsDocument reports whether a line is a JSON document rather than a sentence
+func IsDocument(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "{")
+}
+
+// ParseDocument turns a JSON object, or a YAML mapping when it does not start
+// with {, into its statement. A document that can not be decoded is an error
+// wrapping constant.ErrInvalidDocument, a wrong field is a *FieldError.
+func ParseDocument(text string) (Statement, error) {
+	if IsDocument(text) {
+		fields, err := decodeJSON(text)
+		if err != nil {
+			return nil, err
+		}
+		return parseFields(fields)
+	}
+
+	fields, positions, err := decodeYAML(text)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := parseFields(fields)
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		at := positions[fieldErr.Field]
+		fieldErr.Line, fieldErr.Column = at.line, at.column
+	}
+	return stmt, err
+}
+
+// parseFields turns the fields of a document into its statement
+func parseFields(fields map[string]any) (Statement, error) {
+	d := newDocument(fields, "")
+	op, err := d.word("op", "an operation")
+	if err != nil {
+		return nil, err
+	}
+	operation, exists := operations[op]
+	if !exists {
+		return nil, d.fail("op", "one of "+strings.Join(operationNames(), ", "), describe(fields["op"]), constant.ErrInvalidDocument)
+	}
+
+	stmt, err := operation(d)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.end(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+func decodeJSON(text string) (map[string]any, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("%w: %v", constant.ErrInvalidDocument, err)
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("%w: a document is an object of fields", constant.ErrInvalidDocument)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("%w: a line holds a single document", constant.ErrInvalidDocument)
+	}
+	return fields, nil
+}
+
+func operationNames() []string {
+	names := make([]string, 0, len(operations))
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// document reads the fields of an object, remembering which ones were read so
+// the unknown ones can be reported
+type document struct {
+	fields map[string]any
+	path   string
+	read   map[string]bool
+}
+
+func newDocument(fields map[string]any, path string) *document {
+	return &document{fields: fields, path: path, read: make(map[string]bool)}
+}
+
+func (d *document) field(name string) string {
+	if d.path == "" {
+		return name
+	}
+	return d.path + "." + name
+}
+
+// value returns the field, a null one is missing
+func (d *document) value(name string) (any, bool) {
+	d.read[name] = true
+	value, exists := d.fields[name]
+	return value, exists && value != nil
+}
+
+func (d *document) fail(field, expected, found string, err error) *FieldError {
+	return &FieldError{Field: d.field(field), Expected: expected, Found: found, Err: err}
+}
+
+// end reports the first field, alphabetically, that was never read
+func (d *document) end() error {
+	unknown := make([]string, 0)
+	for name := range d.fields {
+		if !d.read[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return &FieldError{Field: d.field(unknown[0]), Err: constant.ErrInvalidDocument}
+}
+
+// token reads a scalar field as the single token it would be in a sentence
+func (d *document) token(path, expected string, value any, kinds ...TokenKind) (string, error) {
+	text, ok := scalarText(value)
+	tokens := Lex(text)
+	if !ok || len(tokens) != 2 || strings.TrimSpace(text) != text {
+		return "", &FieldError{Field: path, Expected: expected, Found: describe(value), Err: constant.ErrInvalidDocument}
+	}
+	for _, kind := range kinds {
+		if tokens[0].Kind == kind {
+			return strings.ToLower(tokens[0].Text), nil
+		}
+	}
+	return "", &FieldError{Field: path, Expected: expected, Found: describe(value), Err: constant.ErrInvalidDocument}
+}
+
+func (d *document) word(name, expected string) (string, error) {
+	value, exists := d.value(name)
+	if !exists {
+		return "", d.fail(name, expected, "", constant.ErrInvalidDocument)
+	}
+	return d.token(d.field(name), expected, value, Word)
+}
+
+func (d *document) optionalWord(name, expected string) (string, error) {
+	if _, exists := d.value(name); !exists {
+		return "", nil
+	}
+	return d.word(name, expected)
+}
+
+// words reads a list of at least one word
+func (d *document) words(name, expected string) ([]string, error) {
+	value, exists := d.value(name)
+	items, ok := value.([]any)
+	if !exists || !ok || len(items) == 0 {
+		return nil, d.fail(name, "a list of "+expected+"s", describe(value), constant.ErrInvalidDocument)
+	}
+
+	words := make([]string, len(items))
+	for i, item := range items {
+		word, err := d.token(fmt.Sprintf("%s[%d]", d.field(name), i), "a "+expected, item, Word)
+		if err != nil {
+			return nil, err
+		}
+		words[i] = word
+	}
+	return words, nil
+}
+
+// number reads a number written as in sentences, err is the error it wraps
+// when it is not one
+func (d *document) number(name string, err error) (*big.Rat, error) {
+	value, exists := d.value(name)
+	text, ok := scalarText(value)
+	number, valid := new(big.Rat).SetString(text)
+	if !exists || !ok || !numberPattern.MatchString(text) || !valid {
+		return nil, d.fail(name, "a number", describe(value), err)
+	}
+	return number, nil
+}
+
+func (d *document) integer(name string) (int, error) {
+	value, exists := d.value(name)
+	text, ok := scalarText(value)
+	number, convErr := strconv.Atoi(text)
+	if !exists || !ok || !numberPattern.MatchString(text) || convErr != nil {
+		return 0, d.fail(name, "a whole number", describe(value), constant.ErrInvalidFormat)
+	}
+	return number, nil
+}
+
+// date reads an optional day written as YYYY-MM-DD, it is midnight UTC
+func (d *document) date(name string) (*time.Time, error) {
+	value, exists := d.value(name)
+	if !exists {
+		return nil, nil
+	}
+	text, ok := value.(string)
+	at, err := time.Parse(time.DateOnly, text)
+	if !ok || err != nil {
+		return nil, d.fail(name, "a date as YYYY-MM-DD", describe(value), constant.ErrInvalidDocument)
+	}
+	return &at, nil
+}
+
+// object reads a nested object, whose unknown fields are reported with it
+func (d *document) object(name string, read func(*document) error) error {
+	value, _ := d.value(name)
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return d.fail(name, "an object", describe(value), constant.ErrInvalidDocument)
+	}
+
+	nested := newDocument(fields, d.field(name))
+	if err := read(nested); err != nil {
+		return err
+	}
+	return nested.end()
+}
+
+func (d *document) unitDefinition() (Statement, error) {
+	unit, err := d.word("unit", "a unit")
+	if err != nil {
+		return nil, err
+	}
+	value, exists := d.value("roman")
+	if !exists {
+		return nil, d.fail("roman", "a numeral symbol", "", constant.ErrInvalidDocument)
+	}
+	// Mayan digits are symbols written as numbers
+	symbol, err := d.token(d.field("roman"), "a numeral symbol", value, Word, Number)
+	if err != nil {
+		return nil, err
+	}
+
+	return UnitDefinition{Unit: unit, Symbol: symbol}, nil
+}
+
+func (d *document) rateDefinition() (Statement, error) {
+	quantity, err := d.quantity()
+	if err != nil {
+		return nil, err
+	}
+	credits, err := d.number("credits", constant.ErrInvalidCredit)
+	if err != nil {
+		return nil, err
+	}
+
+	return RateDefinition{Units: quantity.Units, Currency: quantity.Currency, Credits: credits}, nil
+}
+
+func (d *document) numeralQuery() (Statement, error) {
+	units, err := d.words("units", "unit")
+	if err != nil {
+		return nil, err
+	}
+	return NumeralQuery{Units: units}, nil
+}
+
+func (d *document) creditQuery() (Statement, error) {
+	quantity, err := d.quantity()
+	if err != nil {
+		return nil, err
+	}
+	at, err := d.date("at")
+	if err != nil {
+		return nil, err
+	}
+
+	return CreditQuery{Units: quantity.Units, Currency: quantity.Currency, At: at}, nil
+}
+
+func (d *document) conversionQuery() (Statement, error) {
+	target, err := d.word("target", "a currency")
+	if err != nil {
+		return nil, err
+	}
+
+	query := ConversionQuery{Target: target}
+	_, hasUnits := d.value("units")
+	_, hasAmount := d.value("amount")
+	switch {
+	case hasUnits && hasAmount:
+		return nil, d.fail("amount", "nothing when units are given", describe(d.fields["amount"]), constant.ErrInvalidDocument)
+	case hasAmount:
+		if query.Amount, err = d.number("amount", constant.ErrInvalidDocument); err != nil {
+			return nil, err
+		}
+	default:
+		if query.Units, err = d.words("units", "unit"); err != nil {
+			return nil, err
+		}
+	}
+
+	if query.Currency, err = d.word("currency", "a currency"); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+func (d *document) purchaseQuery() (Statement, error) {
+	currency, err := d.word("currency", "a currency")
+	if err != nil {
+		return nil, err
+	}
+	credits, err := d.number("credits", constant.ErrInvalidCredit)
+	if err != nil {
+		return nil, err
+	}
+
+	return PurchaseQuery{Currency: currency, Credits: credits}, nil
+}
+
+func (d *document) translationQuery() (Statement, error) {
+	number, err := d.integer("number")
+	if err != nil {
+		return nil, err
+	}
+	system, err := d.optionalWord("system", "a numeral system")
+	if err != nil {
+		return nil, err
+	}
+
+	return TranslationQuery{Number: number, System: system}, nil
+}
+
+func (d *document) unitComparison() (Statement, error) {
+	first, err := d.words("first", "unit")
+	if err != nil {
+		return nil, err
+	}
+	second, err := d.words("second", "unit")
+	if err != nil {
+		return nil, err
+	}
+
+	return UnitComparison{First: first, Second: second}, nil
+}
+
+func (d *document) creditComparison() (Statement, error) {
+	var comparison CreditComparison
+	err := d.object("first", func(nested *document) (err error) {
+		comparison.First, err = nested.quantity()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = d.object("second", func(nested *document) (err error) {
+		comparison.Second, err = nested.quantity()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return comparison, nil
+}
+
+func (d *document) removal() (Statement, error) {
+	name, err := d.word("name", "a unit or a currency")
+	if err != nil {
+		return nil, err
+	}
+	return Removal{Name: name}, nil
+}
+
+func (d *document) listing() (Statement, error) {
+	kind, err := d.word("kind", "units, currencies or history")
+	if err != nil {
+		return nil, err
+	}
+
+	listing := Listing{Kind: kind}
+	switch kind {
+	case "units", "currencies":
+	case "history":
+		if listing.Currency, err = d.word("currency", "a currency"); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, d.fail("kind", "units, currencies or history", describe(d.fields["kind"]), constant.ErrInvalidDocument)
+	}
+
+	return listing, nil
+}
+
+func (d *document) control() (Statement, error) {
+	command, _ := scalarText(d.fields["op"])
+	return Control{Command: strings.ToLower(command)}, nil
+}
+
+func (d *document) namespaceUse() (Statement, error) {
+	name, err := d.word("name", "a namespace")
+	if err != nil {
+		return nil, err
+	}
+	return NamespaceUse{Name: name}, nil
+}
+
+// quantity reads units and the currency they are counted in
+func (d *document) quantity() (Quantity, error) {
+	units, err := d.words("units", "unit")
+	if err != nil {
+		return Quantity{}, err
+	}
+	currency, err := d.word("currency", "a currency")
+	if err != nil {
+		return Quantity{}, err
+	}
+
+	return Quantity{Units: units, Currency: currency}, nil
+}
+
+// scalarText is the text of a string or a number, JSON numbers are kept as
+// written
+func scalarText(value any) (string, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case json.Number:
+		return value.String(), true
+	}
+	return "", false
+}
+
+// describe writes a value found in a document for an error
+func describe(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strconv.Quote(value)
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,241 @@
+package parser
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+func TestParseDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Statement
+	}{
+		{
+			name:     "Unit definition",
+			input:    `{"op":"define_unit","unit":"glob","roman":"I"}`,
+			expected: UnitDefinition{Unit: "glob", Symbol: "i"},
+		},
+		{
+			name:     "Mayan digit definition",
+			input:    `{"op":"define_unit","unit":"glob","roman":17}`,
+			expected: UnitDefinition{Unit: "glob", Symbol: "17"},
+		},
+		{
+			name:  "Rate definition",
+			input: `{"op":"define_rate","units":["glob","glob"],"currency":"Silver","credits":34}`,
+			expected: RateDefinition{
+				Units: []string{"glob", "glob"}, Currency: "silver", Credits: big.NewRat(34, 1),
+			},
+		},
+		{
+			name:  "Rate definition with credits as a string",
+			input: `{"op":"define_rate","units":["glob"],"currency":"Silver","credits":"12.5"}`,
+			expected: RateDefinition{
+				Units: []string{"glob"}, Currency: "silver", Credits: big.NewRat(25, 2),
+			},
+		},
+		{
+			name:     "Value query",
+			input:    `{"op":"query_value","units":["pish","tegj"]}`,
+			expected: NumeralQuery{Units: []string{"pish", "tegj"}},
+		},
+		{
+			name:     "Credits query on a day",
+			input:    `{"op":"query_credits","units":["glob"],"currency":"gold","at":"2026-01-01"}`,
+			expected: CreditQuery{Units: []string{"glob"}, Currency: "gold", At: dateOf(2026, 1, 1)},
+		},
+		{
+			name:     "Conversion of an amount",
+			input:    `{"op":"convert","target":"gold","amount":3,"currency":"silver"}`,
+			expected: ConversionQuery{Target: "gold", Amount: big.NewRat(3, 1), Currency: "silver"},
+		},
+		{
+			name:     "Purchase query",
+			input:    `{"op":"query_purchase","currency":"Silver","credits":500}`,
+			expected: PurchaseQuery{Currency: "silver", Credits: big.NewRat(500, 1)},
+		},
+		{
+			name:     "Translation",
+			input:    `{"op":"translate","number":42,"system":"Mayan"}`,
+			expected: TranslationQuery{Number: 42, System: "mayan"},
+		},
+		{
+			name:  "Credits comparison",
+			input: `{"op":"compare_credits","first":{"units":["glob"],"currency":"silver"},"second":{"units":["prok"],"currency":"gold"}}`,
+			expected: CreditComparison{
+				First:  Quantity{Units: []string{"glob"}, Currency: "silver"},
+				Second: Quantity{Units: []string{"prok"}, Currency: "gold"},
+			},
+		},
+		{
+			name:     "History listing",
+			input:    `{"op":"list","kind":"history","currency":"gold"}`,
+			expected: Listing{Kind: "history", Currency: "gold"},
+		},
+		{
+			name:     "Control",
+			input:    `{"op":"Rollback"}`,
+			expected: Control{Command: "rollback"},
+		},
+		{
+			name:  "YAML rate definition",
+			input: "op: define_rate\nunits:\n  - glob   # first\n  - glob\ncurrency: 'Silver'\ncredits: \"34\"",
+			expected: RateDefinition{
+				Units: []string{"glob", "glob"}, Currency: "silver", Credits: big.NewRat(34, 1),
+			},
+		},
+		{
+			name:  "YAML credits comparison",
+			input: "op: compare_credits\nfirst:\n  units: [glob]\n  currency: silver\nsecond: {units: [prok, glob], currency: gold}",
+			expected: CreditComparison{
+				First:  Quantity{Units: []string{"glob"}, Currency: "silver"},
+				Second: Quantity{Units: []string{"prok", "glob"}, Currency: "gold"},
+			},
+		},
+		{
+			name:     "YAML namespace use",
+			input:    "op: use_namespace\nname: team-a",
+			expected: NamespaceUse{Name: "team-a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseDocument(tt.input)
+			if err != nil {
+				t.Fatalf("ParseDocument() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseDocument() = %#v, want %#v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDocumentFieldError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected *FieldError
+		message  string
+	}{
+		{
+			name:     "Missing op",
+			input:    `{"unit":"glob"}`,
+			expected: &FieldError{Field: "op", Expected: "an operation", Err: constant.ErrInvalidDocument},
+			message:  `invalid document: field "op": expected an operation, found nothing`,
+		},
+		{
+			name:  "Unknown op",
+			input: `{"op":"fly"}`,
+			expected: &FieldError{
+				Field:    "op",
+				Expected: "one of begin, commit, compare_credits, compare_units, convert, define_rate, define_unit, forget, list, query_credits, query_purchase, query_value, redo, rollback, translate, undo, use_namespace",
+				Found:    `"fly"`,
+				Err:      constant.ErrInvalidDocument,
+			},
+			message: `invalid document: field "op": expected one of begin, commit, compare_credits, compare_units, convert, define_rate, define_unit, forget, list, query_credits, query_purchase, query_value, redo, rollback, translate, undo, use_namespace, found "fly"`,
+		},
+		{
+			name:     "Number among the units",
+			input:    `{"op":"query_value","units":["glob",5]}`,
+			expected: &FieldError{Field: "units[1]", Expected: "a unit", Found: "5", Err: constant.ErrInvalidDocument},
+			message:  `invalid document: field "units[1]": expected a unit, found 5`,
+		},
+		{
+			name:     "Units not in a list",
+			input:    `{"op":"query_value","units":"glob prok"}`,
+			expected: &FieldError{Field: "units", Expected: "a list of units", Found: `"glob prok"`, Err: constant.ErrInvalidDocument},
+			message:  `invalid document: field "units": expected a list of units, found "glob prok"`,
+		},
+		{
+			name:     "Credits not a number",
+			input:    `{"op":"define_rate","units":["glob"],"currency":"silver","credits":"lots"}`,
+			expected: &FieldError{Field: "credits", Expected: "a number", Found: `"lots"`, Err: constant.ErrInvalidCredit},
+			message:  `credits is not a number: field "credits": expected a number, found "lots"`,
+		},
+		{
+			name:     "Negative number",
+			input:    `{"op":"translate","number":-4}`,
+			expected: &FieldError{Field: "number", Expected: "a whole number", Found: "-4", Err: constant.ErrInvalidFormat},
+			message:  `requested number is in invalid format: field "number": expected a whole number, found -4`,
+		},
+		{
+			name:     "Nested missing currency",
+			input:    `{"op":"compare_credits","first":{"units":["glob"],"currency":"silver"},"second":{"units":["glob"]}}`,
+			expected: &FieldError{Field: "second.currency", Expected: "a currency", Err: constant.ErrInvalidDocument},
+			message:  `invalid document: field "second.currency": expected a currency, found nothing`,
+		},
+		{
+			name:     "Unknown field",
+			input:    `{"op":"query_value","units":["glob"],"colour":"red"}`,
+			expected: &FieldError{Field: "colour", Err: constant.ErrInvalidDocument},
+			message:  `invalid document: unknown field "colour"`,
+		},
+		{
+			name:     "Both units and amount",
+			input:    `{"op":"convert","target":"gold","units":["glob"],"amount":3,"currency":"silver"}`,
+			expected: &FieldError{Field: "amount", Expected: "nothing when units are given", Found: "3", Err: constant.ErrInvalidDocument},
+			message:  `invalid document: field "amount": expected nothing when units are given, found 3`,
+		},
+		{
+			name:     "YAML date",
+			input:    "op: query_credits\nunits: [glob]\ncurrency: gold\nat: yesterday",
+			expected: &FieldError{Field: "at", Expected: "a date as YYYY-MM-DD", Found: `"yesterday"`, Err: constant.ErrInvalidDocument, Line: 4, Column: 1},
+			message:  `invalid document: field "at": expected a date as YYYY-MM-DD, found "yesterday"`,
+		},
+		{
+			name:     "YAML list item",
+			input:    "op: query_value\nunits:\n  - glob\n  - 3",
+			expected: &FieldError{Field: "units[1]", Expected: "a unit", Found: `"3"`, Err: constant.ErrInvalidDocument, Line: 4, Column: 5},
+			message:  `invalid document: field "units[1]": expected a unit, found "3"`,
+		},
+		{
+			name:     "YAML missing field",
+			input:    "op: query_value\nunit: glob",
+			expected: &FieldError{Field: "units", Expected: "a list of units", Err: constant.ErrInvalidDocument},
+			message:  `invalid document: field "units": expected a list of units, found nothing`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDocument(tt.input)
+
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("ParseDocument() error = %v, want a *FieldError", err)
+			}
+			if !reflect.DeepEqual(fieldErr, tt.expected) {
+				t.Errorf("ParseDocument() error = %#v, want %#v", fieldErr, tt.expected)
+			}
+			if fieldErr.Code() != constant.CodeOf(tt.expected.Err) {
+				t.Errorf("Code() = %s, want %s", fieldErr.Code(), constant.CodeOf(tt.expected.Err))
+			}
+			if fieldErr.Error() != tt.message {
+				t.Errorf("Error() = %s, want %s", fieldErr.Error(), tt.message)
+			}
+		})
+	}
+}
+
+func TestParseDocumentInvalid(t *testing.T) {
+	inputs := []string{
+		`{"op":`,
+		`{"op":"begin"} {"op":"commit"}`,
+		"op define_unit",
+		"op: list\n  kind: units",
+		"- op: begin",
+	}
+
+	for _, input := range inputs {
+		if _, err := ParseDocument(input); !errors.Is(err, constant.ErrInvalidDocument) {
+			t.Errorf("ParseDocument(%q) error = %v, want %v", input, err, constant.ErrInvalidDocument)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+type TokenKind int
+
+const (
+	Word TokenKind = iota
+	Number
+	QuestionMark
+	LeftParen
+	RightParen
+	EOF
+)
+
+type Token struct {
+	Kind TokenKind
+	Text string
+	// Pos is the column of the first character of the token, starting at 1
+	Pos int
+}
+
+var numberPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// Lex splits a line into words, numbers, question marks and parentheses. A
+// question mark or a parenthesis is always its own token, even when glued to
+// a word like "Silver?" or "(glob". The returned tokens always end with an
+// EOF token.
+func Lex(line string) []Token {
+	tokens := make([]Token, 0)
+	runes := []rune(line)
+
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '?':
+			tokens = append(tokens, Token{Kind: QuestionMark, Text: "?", Pos: i + 1})
+			i++
+		case runes[i] == '(':
+			tokens = append(tokens, Token{Kind: LeftParen, Text: "(", Pos: i + 1})
+			i++
+		case runes[i] == ')':
+			tokens = append(tokens, Token{Kind: RightParen, Text: ")", Pos: i + 1})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("?()", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, newWordToken(string(runes[start:i]), start+1))
+		}
+	}
+
+	return append(tokens, Token{Kind: EOF, Pos: len(runes) + 1})
+}
+
+// IsWord reports whether text is read as a single word, as the name of a unit
+// or a currency has to be for sentences to refer to it
+func IsWord(text string) bool {
+	tokens := Lex(text)
+	return len(tokens) == 2 && tokens[0].Kind == Word && tokens[0].Text == text
+}
+
+// IsNumber reports whether text is a number as sentences and documents write
+// credits, digits with an optional decimal part
+func IsNumber(text string) bool {
+	return numberPattern.MatchString(text)
+}
+
+func newWordToken(text string, pos int) Token {
+	if numberPattern.MatchString(text) {
+		return Token{Kind: Number, Text: text, Pos: pos}
+	}
+	return Token{Kind: Word, Text: text, Pos: pos}
+}
+
+// is reports whether the token is the given keyword, ignoring case
+func (t Token) is(keyword string) bool {
+	return t.Kind == Word && strings.EqualFold(t.Text, keyword)
+}
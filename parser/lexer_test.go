@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Token
+	}{
+		{
+			name:  "words and numbers",
+			input: "glob Silver is 12.5 Credits",
+			expected: []Token{
+				{Kind: Word, Text: "glob", Pos: 1},
+				{Kind: Word, Text: "Silver", Pos: 6},
+				{Kind: Word, Text: "is", Pos: 13},
+				{Kind: Number, Text: "12.5", Pos: 16},
+				{Kind: Word, Text: "Credits", Pos: 21},
+				{Kind: EOF, Pos: 28},
+			},
+		},
+		{
+			name:  "question mark glued to a word",
+			input: "how much is glob?",
+			expected: []Token{
+				{Kind: Word, Text: "how", Pos: 1},
+				{Kind: Word, Text: "much", Pos: 5},
+				{Kind: Word, Text: "is", Pos: 10},
+				{Kind: Word, Text: "glob", Pos: 13},
+				{Kind: QuestionMark, Text: "?", Pos: 17},
+				{Kind: EOF, Pos: 18},
+			},
+		},
+		{
+			name:  "parentheses glued to words",
+			input: "(glob plus pish)Silver",
+			expected: []Token{
+				{Kind: LeftParen, Text: "(", Pos: 1},
+				{Kind: Word, Text: "glob", Pos: 2},
+				{Kind: Word, Text: "plus", Pos: 7},
+				{Kind: Word, Text: "pish", Pos: 12},
+				{Kind: RightParen, Text: ")", Pos: 16},
+				{Kind: Word, Text: "Silver", Pos: 17},
+				{Kind: EOF, Pos: 23},
+			},
+		},
+		{
+			name:  "extra whitespace",
+			input: "  glob \t is  I ",
+			expected: []Token{
+				{Kind: Word, Text: "glob", Pos: 3},
+				{Kind: Word, Text: "is", Pos: 10},
+				{Kind: Word, Text: "I", Pos: 14},
+				{Kind: EOF, Pos: 16},
+			},
+		},
+		{
+			name:  "not quite numbers",
+			input: "12. 1e3 -4",
+			expected: []Token{
+				{Kind: Word, Text: "12.", Pos: 1},
+				{Kind: Word, Text: "1e3", Pos: 5},
+				{Kind: Word, Text: "-4", Pos: 9},
+				{Kind: EOF, Pos: 11},
+			},
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: []Token{{Kind: EOF, Pos: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Lex(tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Lex() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsWord(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"glob", true},
+		{"Silver", true},
+		{"glob prok", false},
+		{" glob", false},
+		{"Silver?", false},
+		{"(glob", false},
+		{"12", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if result := IsWord(tt.text); result != tt.expected {
+			t.Errorf("IsWord(%q) = %v, want %v", tt.text, result, tt.expected)
+		}
+	}
+}
+
+func TestIsNumber(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"34", true},
+		{"3910.5", true},
+		{"-34", false},
+		{"1e400", false},
+		{"1E3", false},
+		{"3.", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if result := IsNumber(tt.text); result != tt.expected {
+			t.Errorf("IsNumber(%q) = %v, want %v", tt.text, result, tt.expected)
+		}
+	}
+}
@@ -1,147 +1,710 @@
 package parser
 
 import (
-	"github.com/erizkiatama/prospace-assignment/constant"
+	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
 )
 
-type InputType int
+// The grammar of a line, keywords are matched ignoring case and a unit may be
+// any word, including a keyword, as long as the rule stays unambiguous:
+//
+//	unitDefinition   = word "is" (word | number)
+//	rateDefinition   = word+ word "is" number "credits"
+//	numeralQuery     = "how" "much" "is" amount ["?"]
+//	creditQuery      = "how" "many" "credits" ("is" amount word | "was" amount word "at" date) ["?"]
+//	conversionQuery  = "how" "many" word "is" (number | word+) word ["?"]
+//	purchaseQuery    = "how" "many" word "can" "i" "buy" "with" number "credits" ["?"]
+//	translationQuery = "how" "do" "you" "say" number ["in" word] ["?"]
+//	unitComparison   = "is" word+ ("larger" | "smaller") "than" word+ ["?"]
+//	creditComparison = "does" word+ word "has" ("more" | "less") "credits" "than" word+ word ["?"]
+//	removal          = "forget" word
+//	listing          = "list" ("units" | "currencies" | "history" "of" word)
+//	control          = "begin" | "commit" | "rollback" | "undo" | "redo"
+//	namespaceUse     = "use" "namespace" word
+//
+//	amount           = expression | word+
+//	expression       = term {("plus" | "minus") term}
+//	term             = factor {("times" | "divided" "by") factor}
+//	factor           = word+ | "(" expression ")"
+//
+// A date is written as YYYY-MM-DD. An amount is read as units when it is not
+// a valid expression, so a unit may still be named like an operator.
+//
+// In comparisons the first `larger than` or `has more credits than` ends the
+// first quantity. The rules are tried in this order and the first full match
+// wins.
+var rules = []func(*parser) (Statement, *SyntaxError){
+	(*parser).unitDefinition,
+	(*parser).rateDefinition,
+	(*parser).numeralQuery,
+	(*parser).creditQuery,
+	(*parser).conversionQuery,
+	(*parser).purchaseQuery,
+	(*parser).translationQuery,
+	(*parser).unitComparison,
+	(*parser).creditComparison,
+	(*parser).removal,
+	(*parser).listing,
+	(*parser).control,
+	(*parser).namespaceUse,
+}
 
-const (
-	Assignment InputType = iota
-	Calculation
-	Comparison
-	Invalid
-)
+type parser struct {
+	tokens []Token
+	pos    int
+	// matched counts the tokens up to the last keyword matched, it tells how
+	// far a rule really got, since a run of units may swallow anything
+	matched int
+}
 
-type ItemType int
+// SyntaxError tells where and why a line does not match the grammar. It
+// unwraps to one of the errors in constant.
+type SyntaxError struct {
+	Column   int
+	Token    string
+	Expected []string
+	Err      error
 
-const (
-	Roman ItemType = iota
-	Credits
-)
+	matched int
+}
+
+func (e *SyntaxError) Error() string {
+	found := "end of line"
+	if e.Token != "" {
+		found = strconv.Quote(e.Token)
+	}
+
+	message := fmt.Sprintf("%s: unexpected %s", e.Err, found)
+	if len(e.Expected) > 0 {
+		message += ", expected " + strings.Join(e.Expected, " or ")
+	}
+	return message
+}
+
+// Code is the code of the wrapped error, a parse failure for most lines
+func (e *SyntaxError) Code() constant.Code {
+	return constant.CodeOf(e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// Parse turns a line into its statement. When no rule matches, the error of
+// the rule that matched the most keywords, then stopped the furthest, is
+// returned as a *SyntaxError. The expected tokens of rules that stopped at
+// the same token are merged.
+func Parse(line string) (Statement, error) {
+	tokens := Lex(line)
+
+	var furthest *SyntaxError
+	for _, rule := range rules {
+		stmt, err := rule(&parser{tokens: tokens})
+		if err == nil {
+			return stmt, nil
+		}
+
+		switch {
+		case furthest == nil || err.matched > furthest.matched:
+			furthest = err
+		case err.matched == furthest.matched && err.Column > furthest.Column:
+			furthest = err
+		case err.matched == furthest.matched && err.Column == furthest.Column && err.Err == furthest.Err:
+			furthest.Expected = mergeExpected(furthest.Expected, err.Expected)
+		}
+	}
+
+	return nil, furthest
+}
+
+func mergeExpected(expected, more []string) []string {
+	for _, candidate := range more {
+		found := false
+		for _, existing := range expected {
+			if existing == candidate {
+				found = true
+				break
+			}
+		}
+		if !found {
+			expected = append(expected, candidate)
+		}
+	}
+	return expected
+}
+
+func (p *parser) unitDefinition() (Statement, *SyntaxError) {
+	unit, err := p.word("a unit")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.keywords("is"); err != nil {
+		return nil, err
+	}
+	// Mayan digits are symbols written as numbers
+	if p.peek().Kind != Word && p.peek().Kind != Number {
+		return nil, p.fail(constant.ErrInvalidParse, "a numeral symbol")
+	}
+	symbol := p.next().Text
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+
+	return UnitDefinition{Unit: unit, Symbol: symbol}, nil
+}
+
+func (p *parser) rateDefinition() (Statement, *SyntaxError) {
+	// The quantity is anchored on the `is {credits} credits` tail
+	tail := len(p.tokens) - 4
+	quantity, err := p.quantity(func() bool { return p.pos >= tail })
+	if err != nil {
+		return nil, err
+	}
+	if err := p.keywords("is"); err != nil {
+		return nil, err
+	}
+
+	credits, ok := new(big.Rat).SetString(p.peek().Text)
+	if p.peek().Kind != Number || !ok {
+		return nil, p.fail(constant.ErrInvalidCredit, "a number")
+	}
+	p.next()
+
+	if err := p.keywords("credits"); err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+
+	return RateDefinition{
+		Units:    quantity.Units,
+		Currency: quantity.Currency,
+		Credits:  credits,
+	}, nil
+}
+
+func (p *parser) numeralQuery() (Statement, *SyntaxError) {
+	if err := p.keywords("how", "much", "is"); err != nil {
+		return nil, err
+	}
+	units, expr, err := p.amount(p.atQuestionEnd)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.questionEnd(); err != nil {
+		return nil, err
+	}
+
+	return NumeralQuery{Units: units, Expression: expr}, nil
+}
 
-type ParsedInput struct {
-	InputType      InputType
-	ItemType       ItemType
-	FirstToken     []string
-	SecondToken    []string
-	FirstCurrency  string
-	SecondCurrency string
-	Credits        int
-	Error          error
-}
-
-func Parse(line string) ParsedInput {
-	// Clean up the question mark & trailing whitespace
-	line, _ = strings.CutSuffix(line, "?")
-	line, _ = strings.CutSuffix(line, " ?")
-	line = strings.TrimSpace(line)
-
-	tokens := strings.Split(line, " ")
-	switch {
-	case len(tokens) == 3:
-		return ParsedInput{
-			InputType:  Assignment,
-			ItemType:   Roman,
-			FirstToken: tokens,
-		}
-	case len(tokens) > 4 && tokens[len(tokens)-1] == "credits":
-		first, second, err := parseComparison(line, " is ")
+func (p *parser) creditQuery() (Statement, *SyntaxError) {
+	if err := p.keywords("how", "many", "credits"); err != nil {
+		return nil, err
+	}
+	past := p.peek().is("was")
+	if err := p.oneOfKeywords("is", "was"); err != nil {
+		return nil, err
+	}
+
+	if !past {
+		units, expr, currency, err := p.amountQuantity(p.atQuestionEnd)
 		if err != nil {
-			return ParsedInput{InputType: Invalid, Error: err}
+			return nil, err
+		}
+		if err := p.questionEnd(); err != nil {
+			return nil, err
 		}
+		return CreditQuery{Units: units, Expression: expr, Currency: currency}, nil
+	}
+
+	units, expr, currency, err := p.amountQuantity(func() bool { return p.peek().is("at") })
+	if err != nil {
+		return nil, err
+	}
+	if err := p.keywords("at"); err != nil {
+		return nil, err
+	}
+	at, err := p.date()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.questionEnd(); err != nil {
+		return nil, err
+	}
 
-		credits, err := strconv.Atoi(second[len(second)-2])
+	return CreditQuery{Units: units, Expression: expr, Currency: currency, At: &at}, nil
+}
+
+func (p *parser) conversionQuery() (Statement, *SyntaxError) {
+	if err := p.keywords("how", "many"); err != nil {
+		return nil, err
+	}
+	target, err := p.word("a currency")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.keywords("is"); err != nil {
+		return nil, err
+	}
+
+	var query ConversionQuery
+	if p.peek().Kind == Number {
+		query.Amount, _ = new(big.Rat).SetString(p.next().Text)
+		currency, err := p.word("a currency")
 		if err != nil {
-			return ParsedInput{InputType: Invalid, Error: constant.ErrInvalidCredit}
-		}
-		return ParsedInput{
-			InputType:     Assignment,
-			ItemType:      Credits,
-			FirstToken:    first[:len(first)-1],
-			FirstCurrency: first[len(first)-1],
-			Credits:       credits,
-			Error:         nil,
-		}
-	case strings.HasPrefix(line, "how much is"):
-		return ParsedInput{
-			InputType:  Calculation,
-			ItemType:   Roman,
-			FirstToken: tokens[3:],
-		}
-	case strings.HasPrefix(line, "how many credits is"):
-		return ParsedInput{
-			InputType:     Calculation,
-			ItemType:      Credits,
-			FirstToken:    tokens[4 : len(tokens)-1],
-			FirstCurrency: tokens[len(tokens)-1],
-		}
-	case strings.HasPrefix(line, "is"):
-		separator := ""
-		if strings.Contains(line, "smaller than") {
-			separator = "smaller than"
-		} else if strings.Contains(line, "larger than") {
-			separator = "larger than"
-		}
-
-		line, _ = strings.CutPrefix(line, "is")
-		first, second, err := parseComparison(line, separator)
+			return nil, err
+		}
+		query.Currency = currency
+	} else {
+		quantity, err := p.quantity(p.atQuestionEnd)
 		if err != nil {
-			return ParsedInput{InputType: Invalid, Error: err}
+			return nil, err
 		}
+		query.Units, query.Currency = quantity.Units, quantity.Currency
+	}
 
-		return ParsedInput{
-			InputType:   Comparison,
-			ItemType:    Roman,
-			FirstToken:  first,
-			SecondToken: second,
+	if err := p.questionEnd(); err != nil {
+		return nil, err
+	}
+
+	query.Target = target
+	return query, nil
+}
+
+func (p *parser) purchaseQuery() (Statement, *SyntaxError) {
+	if err := p.keywords("how", "many"); err != nil {
+		return nil, err
+	}
+	currency, err := p.word("a currency")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.keywords("can", "i", "buy", "with"); err != nil {
+		return nil, err
+	}
+
+	credits, ok := new(big.Rat).SetString(p.peek().Text)
+	if p.peek().Kind != Number || !ok {
+		return nil, p.fail(constant.ErrInvalidCredit, "a number")
+	}
+	p.next()
+
+	if err := p.keywords("credits"); err != nil {
+		return nil, err
+	}
+	if err := p.questionEnd(); err != nil {
+		return nil, err
+	}
+
+	return PurchaseQuery{Currency: currency, Credits: credits}, nil
+}
+
+func (p *parser) translationQuery() (Statement, *SyntaxError) {
+	if err := p.keywords("how", "do", "you", "say"); err != nil {
+		return nil, err
+	}
+
+	number, convErr := strconv.Atoi(p.peek().Text)
+	if p.peek().Kind != Number || convErr != nil {
+		return nil, p.fail(constant.ErrInvalidFormat, "a whole number")
+	}
+	p.next()
+
+	system := ""
+	if p.peek().is("in") {
+		p.next()
+		p.matched = p.pos
+		word, err := p.word("a numeral system")
+		if err != nil {
+			return nil, err
 		}
-	case strings.HasPrefix(line, "does"):
-		separator := ""
-		if strings.Contains(line, "less") {
-			separator = "has less credits than"
-		} else if strings.Contains(line, "more") {
-			separator = "has more credits than"
+		system = word
+	}
+
+	if err := p.questionEnd(); err != nil {
+		return nil, err
+	}
+
+	return TranslationQuery{Number: number, System: system}, nil
+}
+
+func (p *parser) unitComparison() (Statement, *SyntaxError) {
+	if err := p.keywords("is"); err != nil {
+		return nil, err
+	}
+	first, err := p.words(1, func() bool {
+		return (p.peek().is("larger") || p.peek().is("smaller")) && p.peekAt(1).is("than")
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.oneOfKeywords("larger", "smaller"); err != nil {
+		return nil, err
+	}
+	if err := p.keywords("than"); err != nil {
+		return nil, err
+	}
+	second, err := p.words(1, p.atQuestionEnd)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.questionEnd(); err != nil {
+		return nil, err
+	}
+
+	return UnitComparison{First: first, Second: second}, nil
+}
+
+func (p *parser) creditComparison() (Statement, *SyntaxError) {
+	if err := p.keywords("does"); err != nil {
+		return nil, err
+	}
+	first, err := p.quantity(func() bool {
+		return p.peek().is("has") &&
+			(p.peekAt(1).is("more") || p.peekAt(1).is("less")) &&
+			p.peekAt(2).is("credits") &&
+			p.peekAt(3).is("than")
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.keywords("has"); err != nil {
+		return nil, err
+	}
+	if err := p.oneOfKeywords("more", "less"); err != nil {
+		return nil, err
+	}
+	if err := p.keywords("credits", "than"); err != nil {
+		return nil, err
+	}
+	second, err := p.quantity(p.atQuestionEnd)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.questionEnd(); err != nil {
+		return nil, err
+	}
+
+	return CreditComparison{First: first, Second: second}, nil
+}
+
+func (p *parser) removal() (Statement, *SyntaxError) {
+	if err := p.keywords("forget"); err != nil {
+		return nil, err
+	}
+	name, err := p.word("a unit or a currency")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+
+	return Removal{Name: name}, nil
+}
+
+func (p *parser) listing() (Statement, *SyntaxError) {
+	if err := p.keywords("list"); err != nil {
+		return nil, err
+	}
+	kind := strings.ToLower(p.peek().Text)
+	if err := p.oneOfKeywords("units", "currencies", "history"); err != nil {
+		return nil, err
+	}
+
+	listing := Listing{Kind: kind}
+	if kind == "history" {
+		if err := p.keywords("of"); err != nil {
+			return nil, err
+		}
+		currency, err := p.word("a currency")
+		if err != nil {
+			return nil, err
 		}
+		listing.Currency = currency
+	}
+
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+
+	return listing, nil
+}
+
+func (p *parser) control() (Statement, *SyntaxError) {
+	command := strings.ToLower(p.peek().Text)
+	if err := p.oneOfKeywords("begin", "commit", "rollback", "undo", "redo"); err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+
+	return Control{Command: command}, nil
+}
+
+func (p *parser) namespaceUse() (Statement, *SyntaxError) {
+	if err := p.keywords("use", "namespace"); err != nil {
+		return nil, err
+	}
+	name, err := p.word("a namespace")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+
+	return NamespaceUse{Name: name}, nil
+}
+
+func (p *parser) peek() Token {
+	return p.peekAt(0)
+}
+
+// peekAt looks ahead without consuming, the EOF token is repeated past the end
+func (p *parser) peekAt(offset int) Token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() Token {
+	token := p.peek()
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+	return token
+}
+
+func (p *parser) fail(err error, expected ...string) *SyntaxError {
+	return &SyntaxError{
+		Column:   p.peek().Pos,
+		Token:    p.peek().Text,
+		Expected: expected,
+		Err:      err,
+		matched:  p.matched,
+	}
+}
+
+func (p *parser) word(what string) (string, *SyntaxError) {
+	if p.peek().Kind != Word {
+		return "", p.fail(constant.ErrInvalidParse, what)
+	}
+	return p.next().Text, nil
+}
+
+// words consumes at least min words, until stop reports true
+func (p *parser) words(min int, stop func() bool) ([]string, *SyntaxError) {
+	words := make([]string, 0)
+	for p.peek().Kind == Word && !stop() {
+		words = append(words, p.next().Text)
+	}
 
-		line, _ = strings.CutPrefix(line, "does")
-		first, second, err := parseComparison(line, separator)
+	if len(words) < min {
+		return nil, p.fail(constant.ErrInvalidParse, "a unit")
+	}
+	return words, nil
+}
+
+// quantity consumes units followed by their currency
+func (p *parser) quantity(stop func() bool) (Quantity, *SyntaxError) {
+	words, err := p.words(1, stop)
+	if err != nil {
+		return Quantity{}, err
+	}
+	if len(words) < 2 {
+		return Quantity{}, p.fail(constant.ErrInvalidParse, "a currency")
+	}
+
+	return Quantity{Units: words[:len(words)-1], Currency: words[len(words)-1]}, nil
+}
+
+// amount consumes units or an arithmetic expression over units, until stop
+// reports true. Either the units or the expression is returned, a lone run of
+// units being units. When the tokens are not a valid expression they are read
+// as units, and the error of the expression is returned if they are not units
+// either.
+func (p *parser) amount(stop func() bool) ([]string, Expression, *SyntaxError) {
+	start, matched := p.pos, p.matched
+	expr, err := p.expression(stop)
+	if err == nil {
+		if operand, ok := expr.(Operand); ok {
+			return operand.Units, nil, nil
+		}
+		return nil, expr, nil
+	}
+
+	p.pos, p.matched = start, matched
+	units, wordsErr := p.words(1, stop)
+	if wordsErr != nil || !stop() {
+		return nil, nil, err
+	}
+	return units, nil, nil
+}
+
+// amountQuantity consumes an amount followed by its currency, the currency
+// being the last word before stop
+func (p *parser) amountQuantity(stop func() bool) ([]string, Expression, string, *SyntaxError) {
+	start := p.pos
+	for !stop() && p.peek().Kind != EOF {
+		p.next()
+	}
+	end := p.pos
+	p.pos = start
+
+	// Without a currency the errors are the ones of a quantity of units
+	if end-start < 2 || p.tokens[end-1].Kind != Word {
+		quantity, err := p.quantity(stop)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		return quantity.Units, nil, quantity.Currency, nil
+	}
+
+	units, expr, err := p.amount(func() bool { return p.pos >= end-1 || stop() })
+	if err != nil {
+		return nil, nil, "", err
+	}
+	currency, err := p.word("a currency")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return units, expr, currency, nil
+}
+
+// expression consumes terms added or subtracted, until stop reports true
+func (p *parser) expression(stop func() bool) (Expression, *SyntaxError) {
+	left, err := p.term(stop)
+	if err != nil {
+		return nil, err
+	}
+	for !stop() && (p.peek().is(Plus) || p.peek().is(Minus)) {
+		operator := strings.ToLower(p.next().Text)
+		p.matched = p.pos
+		right, err := p.term(stop)
+		if err != nil {
+			return nil, err
+		}
+		left = Operation{Operator: operator, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// term consumes factors multiplied or divided, until stop reports true
+func (p *parser) term(stop func() bool) (Expression, *SyntaxError) {
+	left, err := p.factor(stop)
+	if err != nil {
+		return nil, err
+	}
+	for !stop() && (p.peek().is(Times) || p.atDivided()) {
+		operator := Times
+		if p.atDivided() {
+			operator = Divided
+			p.next()
+		}
+		p.next()
+		p.matched = p.pos
+		right, err := p.factor(stop)
 		if err != nil {
-			return ParsedInput{InputType: Invalid, Error: err}
+			return nil, err
 		}
+		left = Operation{Operator: operator, Left: left, Right: right}
+	}
+	return left, nil
+}
 
-		lastIdxFirst, lastIdxSecond := len(first)-1, len(second)-1
-		return ParsedInput{
-			InputType:      Comparison,
-			ItemType:       Credits,
-			FirstToken:     first[:lastIdxFirst],
-			SecondToken:    second[:lastIdxSecond],
-			FirstCurrency:  first[lastIdxFirst],
-			SecondCurrency: second[lastIdxSecond],
+// factor consumes a run of units up to an operator, or an expression in
+// parentheses
+func (p *parser) factor(stop func() bool) (Expression, *SyntaxError) {
+	if p.peek().Kind == LeftParen && !stop() {
+		p.next()
+		p.matched = p.pos
+		expr, err := p.expression(func() bool { return p.peek().Kind == RightParen || stop() })
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().Kind != RightParen {
+			return nil, p.fail(constant.ErrInvalidParse, `")"`)
 		}
-	default:
-		return ParsedInput{
-			InputType: Invalid,
-			Error:     constant.ErrInvalidParse,
+		p.next()
+		p.matched = p.pos
+		return expr, nil
+	}
+
+	units, err := p.words(1, func() bool {
+		return stop() || p.peek().is(Plus) || p.peek().is(Minus) || p.peek().is(Times) || p.atDivided()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Operand{Units: units}, nil
+}
+
+func (p *parser) atDivided() bool {
+	return p.peek().is("divided") && p.peekAt(1).is("by")
+}
+
+// date consumes a day written as YYYY-MM-DD, it is midnight UTC
+func (p *parser) date() (time.Time, *SyntaxError) {
+	at, convErr := time.Parse(time.DateOnly, p.peek().Text)
+	if p.peek().Kind != Word || convErr != nil {
+		return time.Time{}, p.fail(constant.ErrInvalidParse, "a date as YYYY-MM-DD")
+	}
+	p.next()
+	return at, nil
+}
+
+func (p *parser) keywords(keywords ...string) *SyntaxError {
+	for _, keyword := range keywords {
+		if !p.peek().is(keyword) {
+			return p.fail(constant.ErrInvalidParse, strconv.Quote(keyword))
 		}
+		p.next()
+		p.matched = p.pos
 	}
+	return nil
 }
 
-func parseComparison(line, sep string) ([]string, []string, error) {
-	if sep == "" {
-		return nil, nil, constant.ErrInvalidParse
+func (p *parser) oneOfKeywords(keywords ...string) *SyntaxError {
+	for _, keyword := range keywords {
+		if p.peek().is(keyword) {
+			p.next()
+			p.matched = p.pos
+			return nil
+		}
 	}
 
-	before, after, _ := strings.Cut(line, sep)
+	expected := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		expected[i] = strconv.Quote(keyword)
+	}
+	return p.fail(constant.ErrInvalidParse, expected...)
+}
 
-	before = strings.TrimSpace(before)
-	after = strings.TrimSpace(after)
+func (p *parser) atQuestionEnd() bool {
+	return p.peek().Kind == QuestionMark || p.peek().Kind == EOF
+}
 
-	if len(before) == 0 || len(after) == 0 {
-		return nil, nil, constant.ErrInvalidFormat
+// questionEnd consumes the end of a question, where the question mark is optional
+func (p *parser) questionEnd() *SyntaxError {
+	if p.peek().Kind == QuestionMark {
+		p.next()
 	}
+	return p.end()
+}
 
-	return strings.Split(before, " "), strings.Split(after, " "), nil
+func (p *parser) end() *SyntaxError {
+	if p.peek().Kind != EOF {
+		return p.fail(constant.ErrInvalidParse, "end of line")
+	}
+	return nil
 }
@@ -1,148 +1,677 @@
 package parser
 
 import (
-	"github.com/erizkiatama/prospace-assignment/constant"
+	"errors"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
 )
 
 func TestParse(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
-		expected ParsedInput
+		expected Statement
+		err      error
 	}{
 		{
-			name:  "Roman numeral assignment",
-			input: "xyz is I",
-			expected: ParsedInput{
-				InputType:  Assignment,
-				ItemType:   Roman,
-				FirstToken: []string{"xyz", "is", "I"},
-			},
+			name:     "Roman numeral assignment",
+			input:    "xyz is I",
+			expected: UnitDefinition{Unit: "xyz", Symbol: "I"},
+		},
+		{
+			name:     "Mayan digit assignment",
+			input:    "xyz is 17",
+			expected: UnitDefinition{Unit: "xyz", Symbol: "17"},
 		},
 		{
 			name:  "Credits assignment",
 			input: "xyz xyz Silver is 34 credits",
-			expected: ParsedInput{
-				InputType:     Assignment,
-				ItemType:      Credits,
-				FirstToken:    []string{"xyz", "xyz"},
-				FirstCurrency: "Silver",
-				Credits:       34,
+			expected: RateDefinition{
+				Units:    []string{"xyz", "xyz"},
+				Currency: "Silver",
+				Credits:  big.NewRat(34, 1),
 			},
 		},
 		{
-			name:  "Credits assignment error parse comparison",
-			input: " is xyz abc def credits",
-			expected: ParsedInput{
-				InputType: Invalid,
-				Error:     constant.ErrInvalidFormat,
+			name:  "Credits assignment with decimal credits",
+			input: "xyz Silver is 12.5 Credits",
+			expected: RateDefinition{
+				Units:    []string{"xyz"},
+				Currency: "Silver",
+				Credits:  big.NewRat(25, 2),
 			},
 		},
+		{
+			name:  "Credits assignment without units",
+			input: " is xyz abc def credits",
+			err:   constant.ErrInvalidParse,
+		},
 		{
 			name:  "Credits assignment error not a number",
 			input: "xyz xyz is NaN credits",
-			expected: ParsedInput{
-				InputType: Invalid,
-				Error:     constant.ErrInvalidCredit,
+			err:   constant.ErrInvalidCredit,
+		},
+		{
+			name:     "Roman numeral calculation",
+			input:    "how much is jkl rst xyz xyz ?",
+			expected: NumeralQuery{Units: []string{"jkl", "rst", "xyz", "xyz"}},
+		},
+		{
+			name:  "Currency conversion",
+			input: "how many Gold is xyz abc Silver ?",
+			expected: ConversionQuery{
+				Target:   "Gold",
+				Units:    []string{"xyz", "abc"},
+				Currency: "Silver",
 			},
 		},
 		{
-			name:  "Roman numeral calculation",
-			input: "how much is jkl rst xyz xyz ?",
-			expected: ParsedInput{
-				InputType:  Calculation,
-				ItemType:   Roman,
-				FirstToken: []string{"jkl", "rst", "xyz", "xyz"},
+			name:  "Currency conversion of a number",
+			input: "how many Gold is 10.5 Silver ?",
+			expected: ConversionQuery{
+				Target:   "Gold",
+				Amount:   big.NewRat(21, 2),
+				Currency: "Silver",
 			},
 		},
+		{
+			name:  "Currency conversion to credits",
+			input: "how many credits is 10 Silver ?",
+			expected: ConversionQuery{
+				Target:   "credits",
+				Amount:   big.NewRat(10, 1),
+				Currency: "Silver",
+			},
+		},
+		{
+			name:  "Currency conversion without currency",
+			input: "how many Gold is 10 ?",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:     "Purchase",
+			input:    "how many Silver can I buy with 500 Credits ?",
+			expected: PurchaseQuery{Currency: "Silver", Credits: big.NewRat(500, 1)},
+		},
+		{
+			name:     "Purchase with decimal credits",
+			input:    "how many Silver can i buy with 12.5 credits",
+			expected: PurchaseQuery{Currency: "Silver", Credits: big.NewRat(25, 2)},
+		},
+		{
+			name:  "Purchase without credits",
+			input: "how many Silver can I buy with lots Credits ?",
+			err:   constant.ErrInvalidCredit,
+		},
+		{
+			name:  "Purchase without the credits keyword",
+			input: "how many Silver can I buy with 500 ?",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:     "Number translation",
+			input:    "how do you say 1944 ?",
+			expected: TranslationQuery{Number: 1944},
+		},
+		{
+			name:     "Removal",
+			input:    "forget glob",
+			expected: Removal{Name: "glob"},
+		},
+		{
+			name:  "Removal of two names",
+			input: "forget glob prok",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:     "Listing units",
+			input:    "list units",
+			expected: Listing{Kind: "units"},
+		},
+		{
+			name:     "Listing currencies",
+			input:    "List Currencies",
+			expected: Listing{Kind: "currencies"},
+		},
+		{
+			name:     "Listing the history of a currency",
+			input:    "list history of Gold",
+			expected: Listing{Kind: "history", Currency: "Gold"},
+		},
+		{
+			name:  "Listing a history without currency",
+			input: "list history of",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:     "Beginning a block",
+			input:    "Begin",
+			expected: Control{Command: "begin"},
+		},
+		{
+			name:     "Undoing",
+			input:    "undo",
+			expected: Control{Command: "undo"},
+		},
+		{
+			name:  "Undoing twice",
+			input: "undo undo",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:     "Using a namespace",
+			input:    "use namespace team-a",
+			expected: NamespaceUse{Name: "team-a"},
+		},
+		{
+			name:  "Using a namespace without name",
+			input: "use namespace",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:  "Listing something else",
+			input: "list credits",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:     "Number translation in a numeral system",
+			input:    "how do you say 1944 in attic ?",
+			expected: TranslationQuery{Number: 1944, System: "attic"},
+		},
+		{
+			name:  "Number translation without a numeral system",
+			input: "how do you say 1944 in ?",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:  "Number translation not a number",
+			input: "how do you say many ?",
+			err:   constant.ErrInvalidFormat,
+		},
+		{
+			name:  "Number translation not an integer",
+			input: "how do you say 19.5 ?",
+			err:   constant.ErrInvalidFormat,
+		},
 		{
 			name:  "Credits calculation",
 			input: "how many credits is xyz abc Silver ?",
-			expected: ParsedInput{
-				InputType:     Calculation,
-				ItemType:      Credits,
-				FirstToken:    []string{"xyz", "abc"},
-				FirstCurrency: "Silver",
+			expected: CreditQuery{
+				Units:    []string{"xyz", "abc"},
+				Currency: "Silver",
+			},
+		},
+		{
+			name:  "Credits calculation on a day",
+			input: "how many Credits was xyz abc Gold at 2026-01-01 ?",
+			expected: CreditQuery{
+				Units:    []string{"xyz", "abc"},
+				Currency: "Gold",
+				At:       dateOf(2026, 1, 1),
 			},
 		},
+		{
+			name:  "Credits calculation on a day without date",
+			input: "how many credits was xyz Gold ?",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:  "Credits calculation on a date that does not exist",
+			input: "how many credits was xyz Gold at 2026-02-30 ?",
+			err:   constant.ErrInvalidParse,
+		},
 		{
 			name:  "Roman numeral comparison (smaller)",
 			input: "is jkl smaller than xyz ?",
-			expected: ParsedInput{
-				InputType:   Comparison,
-				ItemType:    Roman,
-				FirstToken:  []string{"jkl"},
-				SecondToken: []string{"xyz"},
+			expected: UnitComparison{
+				First:  []string{"jkl"},
+				Second: []string{"xyz"},
 			},
 		},
 		{
 			name:  "Roman numeral comparison (larger)",
 			input: "is xyz abc larger than jkl rst ?",
-			expected: ParsedInput{
-				InputType:   Comparison,
-				ItemType:    Roman,
-				FirstToken:  []string{"xyz", "abc"},
-				SecondToken: []string{"jkl", "rst"},
+			expected: UnitComparison{
+				First:  []string{"xyz", "abc"},
+				Second: []string{"jkl", "rst"},
 			},
 		},
 		{
 			name:  "Roman numeral comparison (error)",
 			input: "is xyz abc invalid jkl rst ?",
-			expected: ParsedInput{
-				InputType: Invalid,
-				Error:     constant.ErrInvalidParse,
-			},
+			err:   constant.ErrInvalidParse,
 		},
 		{
 			name:  "Credits comparison (less)",
 			input: "does xyz abc Silver has less credits than xyz abc Gold ?",
-			expected: ParsedInput{
-				InputType:      Comparison,
-				ItemType:       Credits,
-				FirstToken:     []string{"xyz", "abc"},
-				SecondToken:    []string{"xyz", "abc"},
-				FirstCurrency:  "Silver",
-				SecondCurrency: "Gold",
+			expected: CreditComparison{
+				First:  Quantity{Units: []string{"xyz", "abc"}, Currency: "Silver"},
+				Second: Quantity{Units: []string{"xyz", "abc"}, Currency: "Gold"},
 			},
 		},
 		{
 			name:  "Credits comparison (more)",
 			input: "does xyz abc Gold has more credits than xyz abc Silver ?",
-			expected: ParsedInput{
-				InputType:      Comparison,
-				ItemType:       Credits,
-				FirstToken:     []string{"xyz", "abc"},
-				SecondToken:    []string{"xyz", "abc"},
-				FirstCurrency:  "Gold",
-				SecondCurrency: "Silver",
+			expected: CreditComparison{
+				First:  Quantity{Units: []string{"xyz", "abc"}, Currency: "Gold"},
+				Second: Quantity{Units: []string{"xyz", "abc"}, Currency: "Silver"},
 			},
 		},
 		{
 			name:  "Credits comparison (error)",
 			input: "does xyz abc Gold invalid xyz abc Silver ?",
-			expected: ParsedInput{
-				InputType: Invalid,
-				Error:     constant.ErrInvalidParse,
-			},
+			err:   constant.ErrInvalidParse,
 		},
 		{
 			name:  "Invalid input",
 			input: "how much wood could a woodchuck chuck if a woodchuck could chuck wood ?",
-			expected: ParsedInput{
-				InputType: Invalid,
-				Error:     constant.ErrInvalidParse,
+			err:   constant.ErrInvalidParse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(tt.input)
+			if !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Parse() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestParseTrickyInputs covers inputs the old token counting parser got wrong
+func TestParseTrickyInputs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Statement
+		err      error
+	}{
+		{
+			name:     "unit named like a comparison keyword",
+			input:    "less is I",
+			expected: UnitDefinition{Unit: "less", Symbol: "I"},
+		},
+		{
+			name:  "unit named less in a credits comparison",
+			input: "does less Silver has more credits than glob Gold ?",
+			expected: CreditComparison{
+				First:  Quantity{Units: []string{"less"}, Currency: "Silver"},
+				Second: Quantity{Units: []string{"glob"}, Currency: "Gold"},
+			},
+		},
+		{
+			name:  "unit named more in a credits comparison",
+			input: "does glob Silver has less credits than more Gold ?",
+			expected: CreditComparison{
+				First:  Quantity{Units: []string{"glob"}, Currency: "Silver"},
+				Second: Quantity{Units: []string{"more"}, Currency: "Gold"},
+			},
+		},
+		{
+			name:  "unit named larger in a unit comparison",
+			input: "is larger smaller than glob ?",
+			expected: UnitComparison{
+				First:  []string{"larger"},
+				Second: []string{"glob"},
+			},
+		},
+		{
+			name:     "unit named is",
+			input:    "how much is is ?",
+			expected: NumeralQuery{Units: []string{"is"}},
+		},
+		{
+			name:     "unit named how",
+			input:    "how is V",
+			expected: UnitDefinition{Unit: "how", Symbol: "V"},
+		},
+		{
+			name:     "three words question",
+			input:    "how much is glob",
+			expected: NumeralQuery{Units: []string{"glob"}},
+		},
+		{
+			name:  "three words question without units",
+			input: "how much is ?",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:  "three words question without question mark and units",
+			input: "how much is",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:     "question mark glued to the last word",
+			input:    "how much is glob prok?",
+			expected: NumeralQuery{Units: []string{"glob", "prok"}},
+		},
+		{
+			name:     "keywords in any case",
+			input:    "HOW MUCH IS glob ?",
+			expected: NumeralQuery{Units: []string{"glob"}},
+		},
+		{
+			name:  "currency named credits",
+			input: "glob credits is 10 credits",
+			expected: RateDefinition{
+				Units:    []string{"glob"},
+				Currency: "credits",
+				Credits:  big.NewRat(10, 1),
 			},
 		},
+		{
+			name:  "credits query without currency",
+			input: "how many credits is glob ?",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:  "definition with a question mark",
+			input: "glob is I ?",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:  "is glued to the first unit",
+			input: "istegj glob glob smaller than glob prok ?",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:  "number used as a unit",
+			input: "how much is 5 ?",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:  "words after the question mark",
+			input: "how much is glob ? prok",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:  "empty line",
+			input: "",
+			err:   constant.ErrInvalidParse,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := Parse(tt.input)
+			result, err := Parse(tt.input)
+			if !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.err)
+			}
 			if !reflect.DeepEqual(result, tt.expected) {
 				t.Errorf("Parse() = %v, want %v", result, tt.expected)
 			}
 		})
 	}
 }
+
+func TestParseExpressions(t *testing.T) {
+	glob, prok, pish := Operand{Units: []string{"glob"}}, Operand{Units: []string{"prok"}}, Operand{Units: []string{"pish"}}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected Statement
+		text     string
+		err      error
+	}{
+		{
+			name:  "sum of units",
+			input: "how much is pish tegj plus glob glob ?",
+			expected: NumeralQuery{Expression: Operation{
+				Operator: Plus, Left: Operand{Units: []string{"pish", "tegj"}}, Right: Operand{Units: []string{"glob", "glob"}},
+			}},
+			text: "pish tegj plus glob glob",
+		},
+		{
+			name:  "times before minus",
+			input: "how much is pish minus glob TIMES prok ?",
+			expected: NumeralQuery{Expression: Operation{
+				Operator: Minus, Left: pish, Right: Operation{Operator: Times, Left: glob, Right: prok},
+			}},
+			text: "pish minus glob times prok",
+		},
+		{
+			name:  "operators of the same precedence from the left",
+			input: "how much is pish divided by prok divided by glob ?",
+			expected: NumeralQuery{Expression: Operation{
+				Operator: Divided, Left: Operation{Operator: Divided, Left: pish, Right: prok}, Right: glob,
+			}},
+			text: "pish divided by prok divided by glob",
+		},
+		{
+			name:  "parentheses",
+			input: "how much is (pish plus glob) times (prok minus glob)?",
+			expected: NumeralQuery{Expression: Operation{
+				Operator: Times,
+				Left:     Operation{Operator: Plus, Left: pish, Right: glob},
+				Right:    Operation{Operator: Minus, Left: prok, Right: glob},
+			}},
+			text: "(pish plus glob) times (prok minus glob)",
+		},
+		{
+			name:  "parentheses on the right",
+			input: "how much is pish minus (prok minus glob) ?",
+			expected: NumeralQuery{Expression: Operation{
+				Operator: Minus, Left: pish, Right: Operation{Operator: Minus, Left: prok, Right: glob},
+			}},
+			text: "pish minus (prok minus glob)",
+		},
+		{
+			name:     "units in parentheses",
+			input:    "how much is (glob prok) ?",
+			expected: NumeralQuery{Units: []string{"glob", "prok"}},
+		},
+		{
+			name:  "credits of a sum",
+			input: "how many Credits is (glob prok plus pish) Silver ?",
+			expected: CreditQuery{
+				Expression: Operation{Operator: Plus, Left: Operand{Units: []string{"glob", "prok"}}, Right: pish},
+				Currency:   "Silver",
+			},
+			text: "glob prok plus pish",
+		},
+		{
+			name:  "credits of a product without parentheses",
+			input: "how many Credits is glob times prok Silver ?",
+			expected: CreditQuery{
+				Expression: Operation{Operator: Times, Left: glob, Right: prok},
+				Currency:   "Silver",
+			},
+			text: "glob times prok",
+		},
+		{
+			name:  "credits of a sum on a day",
+			input: "how many Credits was glob plus glob Silver at 2025-01-31 ?",
+			expected: CreditQuery{
+				Expression: Operation{Operator: Plus, Left: glob, Right: glob},
+				Currency:   "Silver",
+				At:         dateOf(2025, time.January, 31),
+			},
+			text: "glob plus glob",
+		},
+		{
+			name:     "unit named like an operator",
+			input:    "how much is glob plus ?",
+			expected: NumeralQuery{Units: []string{"glob", "plus"}},
+		},
+		{
+			name:     "unit named divided",
+			input:    "how much is divided glob ?",
+			expected: NumeralQuery{Units: []string{"divided", "glob"}},
+		},
+		{
+			name:  "operand in parentheses",
+			input: "how much is glob plus (prok) ?",
+			expected: NumeralQuery{Expression: Operation{
+				Operator: Plus, Left: glob, Right: prok,
+			}},
+			text: "glob plus prok",
+		},
+		{
+			name:  "unclosed parenthesis",
+			input: "how much is (glob plus prok ?",
+			err:   constant.ErrInvalidParse,
+		},
+		{
+			name:  "credits of a sum without currency",
+			input: "how many Credits is glob plus (prok) ?",
+			err:   constant.ErrInvalidParse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(tt.input)
+			if !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Parse() = %v, want %v", result, tt.expected)
+			}
+
+			var expr Expression
+			switch stmt := result.(type) {
+			case NumeralQuery:
+				expr = stmt.Expression
+			case CreditQuery:
+				expr = stmt.Expression
+			}
+			if expr != nil && expr.String() != tt.text {
+				t.Errorf("String() = %q, want %q", expr.String(), tt.text)
+			}
+		})
+	}
+}
+
+func TestOperands(t *testing.T) {
+	expr := Operation{
+		Operator: Times,
+		Left:     Operation{Operator: Plus, Left: Operand{Units: []string{"pish"}}, Right: Operand{Units: []string{"glob"}}},
+		Right:    Operand{Units: []string{"prok", "glob"}},
+	}
+	expected := []Operand{{Units: []string{"pish"}}, {Units: []string{"glob"}}, {Units: []string{"prok", "glob"}}}
+
+	if operands := Operands(expr); !reflect.DeepEqual(operands, expected) {
+		t.Errorf("Operands() = %v, want %v", operands, expected)
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected *SyntaxError
+		message  string
+	}{
+		{
+			name:  "unknown sentence",
+			input: "how much wood could a woodchuck chuck ?",
+			expected: &SyntaxError{
+				Column:   10,
+				Token:    "wood",
+				Expected: []string{`"is"`},
+				Err:      constant.ErrInvalidParse,
+			},
+			message: `i have no idea what are you talking about: unexpected "wood", expected "is"`,
+		},
+		{
+			name:  "missing comparison keyword",
+			input: "is glob bigger than prok ?",
+			expected: &SyntaxError{
+				Column:   26,
+				Token:    "?",
+				Expected: []string{`"larger"`, `"smaller"`},
+				Err:      constant.ErrInvalidParse,
+			},
+			message: `i have no idea what are you talking about: unexpected "?", expected "larger" or "smaller"`,
+		},
+		{
+			name:  "missing currency",
+			input: "does glob Silver has more credits than glob",
+			expected: &SyntaxError{
+				Column:   44,
+				Expected: []string{"a currency"},
+				Err:      constant.ErrInvalidParse,
+			},
+			message: `i have no idea what are you talking about: unexpected end of line, expected a currency`,
+		},
+		{
+			name:  "credits not a number",
+			input: "glob glob Silver is lots Credits",
+			expected: &SyntaxError{
+				Column:   21,
+				Token:    "lots",
+				Expected: []string{"a number"},
+				Err:      constant.ErrInvalidCredit,
+			},
+			message: `credits is not a number: unexpected "lots", expected a number`,
+		},
+		{
+			name:  "credits on a day not written as a date",
+			input: "how many credits was glob Gold at noon",
+			expected: &SyntaxError{
+				Column:   35,
+				Token:    "noon",
+				Expected: []string{"a date as YYYY-MM-DD"},
+				Err:      constant.ErrInvalidParse,
+			},
+			message: `i have no idea what are you talking about: unexpected "noon", expected a date as YYYY-MM-DD`,
+		},
+		{
+			name:  "unclosed parenthesis",
+			input: "how much is (glob plus prok ?",
+			expected: &SyntaxError{
+				Column:   29,
+				Token:    "?",
+				Expected: []string{`")"`},
+				Err:      constant.ErrInvalidParse,
+			},
+			message: `i have no idea what are you talking about: unexpected "?", expected ")"`,
+		},
+		{
+			name:  "definition asked as a question",
+			input: "glob is I ?",
+			expected: &SyntaxError{
+				Column:   11,
+				Token:    "?",
+				Expected: []string{"end of line"},
+				Err:      constant.ErrInvalidParse,
+			},
+			message: `i have no idea what are you talking about: unexpected "?", expected end of line`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse() error = %v, want a *SyntaxError", err)
+			}
+			syntaxErr.matched = 0
+			if !reflect.DeepEqual(syntaxErr, tt.expected) {
+				t.Errorf("Parse() error = %#v, want %#v", syntaxErr, tt.expected)
+			}
+			if syntaxErr.Code() != constant.CodeOf(tt.expected.Err) {
+				t.Errorf("Code() = %s, want %s", syntaxErr.Code(), constant.CodeOf(tt.expected.Err))
+			}
+			if syntaxErr.Error() != tt.message {
+				t.Errorf("Error() = %s, want %s", syntaxErr.Error(), tt.message)
+			}
+		})
+	}
+}
+
+func dateOf(year int, month time.Month, day int) *time.Time {
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &date
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+// maxYAMLValues caps the values a document expands to, so aliases nested in
+// one another can not blow a few lines up into millions of values
+const maxYAMLValues = 1000
+
+// position is where a field is written in a document, line and column
+// counting from 1
+type position struct {
+	line   int
+	column int
+}
+
+// decodeYAML reads a YAML document that is a mapping. Every scalar is read as
+// the text it is written with, as numbers and days are checked by the fields
+// reading them, and null as nothing. The position of every field is returned
+// by its path, e.g. units[1] or first.currency.
+func decodeYAML(text string) (map[string]any, map[string]position, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+
+	var root yaml.Node
+	if err := decoder.Decode(&root); errors.Is(err, io.EOF) || (err == nil && len(root.Content) == 0) {
+		return nil, nil, fmt.Errorf("%w: the document is empty", constant.ErrInvalidDocument)
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", constant.ErrInvalidDocument, strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	var next yaml.Node
+	if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("%w: a document is read at a time", constant.ErrInvalidDocument)
+	}
+
+	r := &yamlReader{positions: make(map[string]position), visiting: make(map[*yaml.Node]bool)}
+	value, err := r.value(root.Content[0], "")
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return nil, nil, yamlError(root.Content[0], "a document is a mapping of fields")
+	}
+	return fields, r.positions, nil
+}
+
+// yamlReader turns nodes into the values a JSON document decodes to,
+// following aliases
+type yamlReader struct {
+	positions map[string]position
+	// visiting holds the anchored lists and mappings being read, an alias to
+	// one of them is inside itself
+	visiting map[*yaml.Node]bool
+	values   int
+}
+
+func (r *yamlReader) value(node *yaml.Node, path string) (any, error) {
+	r.values++
+	if r.values > maxYAMLValues {
+		return nil, yamlFieldError(node, path, fmt.Sprintf("at most %d values", maxYAMLValues), "more")
+	}
+
+	switch node.Kind {
+	case yaml.AliasNode:
+		if r.visiting[node.Alias] {
+			return nil, yamlFieldError(node, path, "a value", "an alias of itself")
+		}
+		return r.value(node.Alias, path)
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!null" {
+			return nil, nil
+		}
+		return node.Value, nil
+	case yaml.SequenceNode:
+		if node.Anchor != "" {
+			r.visiting[node] = true
+			defer delete(r.visiting, node)
+		}
+		items := make([]any, len(node.Content))
+		for i, item := range node.Content {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			r.positions[itemPath] = position{line: item.Line, column: item.Column}
+			value, err := r.value(item, itemPath)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+	case yaml.MappingNode:
+		if node.Anchor != "" {
+			r.visiting[node] = true
+			defer delete(r.visiting, node)
+		}
+		fields := make(map[string]any, len(node.Content)/2)
+		for i := 0; i < len(node.Content); i += 2 {
+			key, item := node.Content[i], node.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				return nil, yamlError(key, "a field name is a plain word")
+			}
+			if _, exists := fields[key.Value]; exists {
+				return nil, yamlError(key, fmt.Sprintf("field %q is written twice", key.Value))
+			}
+			fieldPath := key.Value
+			if path != "" {
+				fieldPath = path + "." + key.Value
+			}
+			r.positions[fieldPath] = position{line: key.Line, column: key.Column}
+			value, err := r.value(item, fieldPath)
+			if err != nil {
+				return nil, err
+			}
+			fields[key.Value] = value
+		}
+		return fields, nil
+	}
+	return nil, yamlError(node, "unexpected node")
+}
+
+func yamlError(node *yaml.Node, message string) error {
+	return fmt.Errorf("%w: line %d: %s", constant.ErrInvalidDocument, node.Line, message)
+}
+
+func yamlFieldError(node *yaml.Node, path, expected, found string) *FieldError {
+	return &FieldError{
+		Field: path, Expected: expected, Found: found, Err: constant.ErrInvalidDocument,
+		Line: node.Line, Column: node.Column,
+	}
+}
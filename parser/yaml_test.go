@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/erizkiatama/prospace-assignment/constant"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]any
+	}{
+		{
+			name:     "Scalars",
+			input:    "op: define_unit\nunit: \"glob\"\nroman: 'I'\nsystem: ~",
+			expected: map[string]any{"op": "define_unit", "unit": "glob", "roman": "I", "system": nil},
+		},
+		{
+			name:     "Comments",
+			input:    "# a unit\nop: define_unit # inline\nunit: 'gl#ob'\n",
+			expected: map[string]any{"op": "define_unit", "unit": "gl#ob"},
+		},
+		{
+			name:     "Block sequence",
+			input:    "units:\n  - glob\n  - prok",
+			expected: map[string]any{"units": []any{"glob", "prok"}},
+		},
+		{
+			name:     "Block sequence at the key indentation",
+			input:    "units:\n- glob\n- prok\ncurrency: gold",
+			expected: map[string]any{"units": []any{"glob", "prok"}, "currency": "gold"},
+		},
+		{
+			name:     "Flow sequence",
+			input:    "units: [glob, 'prok', \"pish\"]",
+			expected: map[string]any{"units": []any{"glob", "prok", "pish"}},
+		},
+		{
+			name:  "Nested mappings",
+			input: "first:\n  units: [glob]\n  currency: silver\nsecond: {units: [prok], currency: gold}",
+			expected: map[string]any{
+				"first":  map[string]any{"units": []any{"glob"}, "currency": "silver"},
+				"second": map[string]any{"units": []any{"prok"}, "currency": "gold"},
+			},
+		},
+		{
+			name:     "Numbers and days are read as written",
+			input:    "credits: 3.50\nnumber: 0x10\nat: 2026-01-01\nroman: yes",
+			expected: map[string]any{"credits": "3.50", "number": "0x10", "at": "2026-01-01", "roman": "yes"},
+		},
+		{
+			name:     "Anchors and block scalars",
+			input:    "units: &pair [glob, glob]\nsame: *pair\nname: >-\n  team-a",
+			expected: map[string]any{"units": []any{"glob", "glob"}, "same": []any{"glob", "glob"}, "name": "team-a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _, err := decodeYAML(tt.input)
+			if err != nil {
+				t.Fatalf("decodeYAML() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("decodeYAML() = %#v, want %#v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeYAMLError(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		message string
+	}{
+		{"Empty", "# nothing\n", "invalid document: the document is empty"},
+		{"Sequence document", "- glob", "invalid document: line 1: a document is a mapping of fields"},
+		{"Missing colon", "op: begin\nunit glob", "invalid document: line 2: could not find expected ':'"},
+		{"Duplicate field", "op: begin\nop: commit", `invalid document: line 2: field "op" is written twice`},
+		{"Bad indentation", "op: begin\n  unit: glob", "invalid document: line 2: mapping values are not allowed in this context"},
+		{"Tab indentation", "first:\n\tunits: glob", "invalid document: line 2: found character that cannot start any token"},
+		{"Several documents", "op: begin\n---\nop: commit", "invalid document: a document is read at a time"},
+		{"Unclosed list", "units: [glob, prok", "invalid document: line 1: did not find expected ',' or ']'"},
+		{"Recursive alias", "op: define_unit\nunit: &a [x, *a]", `invalid document: field "unit[1]": expected a value, found an alias of itself`},
+		{"Recursive mapping alias", "first: &a\n  units: [glob]\n  second: *a", `invalid document: field "first.second": expected a value, found an alias of itself`},
+		{
+			"Nested aliases",
+			"a: &a [x, x, x, x, x, x, x, x, x, x]\nb: &b [*a, *a, *a, *a, *a, *a, *a, *a, *a, *a]\nc: &c [*b, *b, *b, *b, *b, *b, *b, *b, *b, *b]\nd: [*c, *c, *c, *c, *c, *c, *c, *c, *c, *c]",
+			`invalid document: field "c[7][0][8]": expected at most 1000 values, found more`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeYAML(tt.input)
+			if !errors.Is(err, constant.ErrInvalidDocument) {
+				t.Fatalf("decodeYAML() error = %v, want %v", err, constant.ErrInvalidDocument)
+			}
+			if err.Error() != tt.message {
+				t.Errorf("Error() = %s, want %s", err.Error(), tt.message)
+			}
+		})
+	}
+}
+
+func TestDecodeYAMLPositions(t *testing.T) {
+	input := "op: define_rate\nunits:\n  - glob\n  - prok\ncurrency: &c silver\nfirst: {currency: *c}"
+
+	_, positions, err := decodeYAML(input)
+	if err != nil {
+		t.Fatalf("decodeYAML() error = %v", err)
+	}
+
+	expected := map[string]position{
+		"op":             {1, 1},
+		"units":          {2, 1},
+		"units[0]":       {3, 5},
+		"units[1]":       {4, 5},
+		"currency":       {5, 1},
+		"first":          {6, 1},
+		"first.currency": {6, 9},
+	}
+	if !reflect.DeepEqual(positions, expected) {
+		t.Errorf("decodeYAML() positions = %v, want %v", positions, expected)
+	}
+}
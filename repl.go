@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"github.com/erizkiatama/prospace-assignment/calculator"
+)
+
+// maxHistory bounds both the history kept in memory and the lines loaded back
+const maxHistory = 1000
+
+// runREPL answers lines typed on a terminal, with line editing, history kept
+// in historyPath between sessions, and tab completion of the units and
+// currencies defined in the namespace in use.
+func runREPL(workspace calculator.Workspace, historyPath string, options sessionOptions) error {
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, oldState)
+
+	terminal := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "> ")
+	terminal.History = loadHistory(historyPath)
+
+	s, err := newSession(workspace, "", terminal, options)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	terminal.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		return complete(append(s.journal.ListUnits(), s.journal.ListCurrencies()...), line, pos)
+	}
+	// The greeting is not a statement, it has no JSON record
+	if options.output == textOutput {
+		fmt.Fprintln(terminal, `Type "help" for the supported sentences, "quit" to exit.`)
+	}
+	for {
+		line, err := terminal.ReadLine()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !s.handleLine(line) {
+			return nil
+		}
+	}
+}
+
+// complete replaces the word before pos by the longest prefix shared by the
+// candidates starting with it, a word may follow an opening parenthesis. A
+// single match is completed with a trailing space, or moves past the space
+// already there, so the next word can be typed right away.
+func complete(candidates []string, line string, pos int) (string, int, bool) {
+	start := strings.LastIndexAny(line[:pos], " (") + 1
+	prefix := strings.ToLower(line[start:pos])
+
+	common := ""
+	matches := 0
+	for _, candidate := range candidates {
+		if !strings.HasPrefix(candidate, prefix) {
+			continue
+		}
+		matches++
+		if matches == 1 {
+			common = candidate
+			continue
+		}
+		// Whole runes are taken off, so a name like glöb is never cut inside ö
+		for !strings.HasPrefix(candidate, common) {
+			_, size := utf8.DecodeLastRuneInString(common)
+			common = common[:len(common)-size]
+		}
+	}
+
+	rest := line[pos:]
+	switch {
+	case matches == 0, matches > 1 && common == prefix:
+		return "", 0, false
+	case matches == 1:
+		common += " "
+		rest = strings.TrimPrefix(rest, " ")
+	}
+	return line[:start] + common + rest, start + len(common), true
+}
+
+// fileHistory is a term.History that also appends every line to a file. The
+// file is rewritten with the entries kept once it holds more than maxHistory
+// lines, so it does not grow forever.
+type fileHistory struct {
+	path    string
+	entries []string
+	// lines counts the lines of the file, blank ones included
+	lines int
+}
+
+func loadHistory(path string) *fileHistory {
+	history := &fileHistory{path: path}
+	if path == "" {
+		return history
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return history
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		history.remember(scanner.Text())
+		history.lines++
+	}
+	return history
+}
+
+func (h *fileHistory) remember(entry string) {
+	if strings.TrimSpace(entry) == "" {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+}
+
+func (h *fileHistory) Add(entry string) {
+	h.remember(entry)
+	if h.path == "" || strings.TrimSpace(entry) == "" {
+		return
+	}
+
+	// History is a convenience, failing to save it must not end the session
+	if h.lines >= maxHistory {
+		h.rewrite()
+		return
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if _, err := file.WriteString(entry + "\n"); err == nil {
+		h.lines++
+	}
+}
+
+// rewrite replaces the file with the entries kept in memory
+func (h *fileHistory) rewrite() {
+	content := strings.Join(h.entries, "\n") + "\n"
+	if err := os.WriteFile(h.path, []byte(content), 0o600); err == nil {
+		h.lines = len(h.entries)
+	}
+}
+
+func (h *fileHistory) Len() int {
+	return len(h.entries)
+}
+
+// At counts from the most recent entry
+func (h *fileHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestComplete(t *testing.T) {
+	candidates := []string{"glob", "globe", "prok", "silver"}
+
+	tests := []struct {
+		name        string
+		line        string
+		pos         int
+		expected    string
+		expectedPos int
+		ok          bool
+	}{
+		{"single match", "how much is pr", 14, "how much is prok ", 17, true},
+		{"common prefix", "how many Credits is g", 21, "how many Credits is glob", 24, true},
+		{"case insensitive", "how much is PR", 14, "how much is prok ", 17, true},
+		{"in the middle of a line", "is pr larger than glob", 5, "is prok larger than glob", 8, true},
+		{"after a parenthesis", "how much is (pr", 15, "how much is (prok ", 18, true},
+		{"no match", "how much is xyz", 15, "", 0, false},
+		{"nothing to add", "how much is glob", 16, "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, pos, ok := complete(candidates, tt.line, tt.pos)
+			if ok != tt.ok || line != tt.expected || pos != tt.expectedPos {
+				t.Errorf("complete() = %q, %d, %v, want %q, %d, %v", line, pos, ok, tt.expected, tt.expectedPos, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCompleteMultiByte(t *testing.T) {
+	// ö and ä share their first byte
+	candidates := []string{"glöb", "glöbel", "gläb"}
+
+	tests := []struct {
+		name        string
+		line        string
+		pos         int
+		expected    string
+		expectedPos int
+		ok          bool
+	}{
+		{"runes differing after a shared byte", "how much is gl", 14, "", 0, false},
+		{"common prefix", "how much is glö", 16, "how much is glöb", 17, true},
+		{"single match", "how much is glöbe", 18, "how much is glöbel ", 20, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, pos, ok := complete(candidates, tt.line, tt.pos)
+			if ok != tt.ok || line != tt.expected || pos != tt.expectedPos {
+				t.Errorf("complete() = %q, %d, %v, want %q, %d, %v", line, pos, ok, tt.expected, tt.expectedPos, tt.ok)
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("complete() = %q, not valid UTF-8", line)
+			}
+		})
+	}
+}
+
+func TestFileHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("glob is I\n\nprok is V\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	history := loadHistory(path)
+	history.Add("how much is glob prok ?")
+	history.Add("  ")
+
+	if history.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", history.Len())
+	}
+	if history.At(0) != "how much is glob prok ?" || history.At(2) != "glob is I" {
+		t.Errorf("At() = %q, %q, want most recent first", history.At(0), history.At(2))
+	}
+
+	// A new session sees the lines added by the previous one
+	reloaded := loadHistory(path)
+	if reloaded.Len() != 3 || reloaded.At(0) != "how much is glob prok ?" {
+		t.Errorf("reloaded history = %v, want the added line last", reloaded.entries)
+	}
+}
+
+func TestFileHistoryIsBounded(t *testing.T) {
+	history := loadHistory("")
+	for i := 0; i < maxHistory+10; i++ {
+		history.Add("how much is glob ?")
+	}
+
+	if history.Len() != maxHistory {
+		t.Errorf("Len() = %d, want %d", history.Len(), maxHistory)
+	}
+}
+
+func TestFileHistoryFileIsBounded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	history := loadHistory(path)
+	for i := 0; i < maxHistory+10; i++ {
+		history.Add(fmt.Sprintf("how much is glob ? # %d", i))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != maxHistory {
+		t.Fatalf("history file has %d lines, want %d", len(lines), maxHistory)
+	}
+	if last := fmt.Sprintf("how much is glob ? # %d", maxHistory+9); lines[len(lines)-1] != last {
+		t.Errorf("last line = %q, want %q", lines[len(lines)-1], last)
+	}
+
+	reloaded := loadHistory(path)
+	if reloaded.Len() != maxHistory || reloaded.At(0) != history.At(0) {
+		t.Errorf("reloaded history has %d entries, most recent %q", reloaded.Len(), reloaded.At(0))
+	}
+}
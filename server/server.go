@@ -0,0 +1,450 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/calculator"
+	"github.com/erizkiatama/prospace-assignment/constant"
+	"github.com/erizkiatama/prospace-assignment/database"
+	"github.com/erizkiatama/prospace-assignment/parser"
+)
+
+type server struct {
+	db   database.Database
+	calc calculator.Calculator
+}
+
+type unitRequest struct {
+	Unit  string `json:"unit"`
+	Roman string `json:"roman"`
+}
+
+type unitResponse struct {
+	Unit    string `json:"unit"`
+	Roman   string `json:"roman"`
+	Warning string `json:"warning,omitempty"`
+	// Stale lists the currencies whose rate could not follow the change
+	Stale []string `json:"stale,omitempty"`
+}
+
+// ratePointResponse is a rate of a currency, Since is empty for a rate kept
+// from before history was recorded
+type ratePointResponse struct {
+	Since string `json:"since,omitempty"`
+	Rate  string `json:"rate"`
+}
+
+type currencyRequest struct {
+	Units    []string    `json:"units"`
+	Currency string      `json:"currency"`
+	Credits  json.Number `json:"credits"`
+}
+
+type currencyResponse struct {
+	Currency string `json:"currency"`
+	Rate     string `json:"rate"`
+	Warning  string `json:"warning,omitempty"`
+}
+
+type convertRequest struct {
+	Units []string `json:"units"`
+}
+
+type convertResponse struct {
+	Units []string `json:"units"`
+	Value int      `json:"value"`
+}
+
+type sayRequest struct {
+	Number int    `json:"number"`
+	System string `json:"system"`
+}
+
+type creditsRequest struct {
+	Units    []string `json:"units"`
+	Currency string   `json:"currency"`
+}
+
+// creditsQuery asks for the credits on a day when At is set, as YYYY-MM-DD
+type creditsQuery struct {
+	creditsRequest
+	At string `json:"at"`
+}
+
+type creditsResponse struct {
+	Units    []string `json:"units"`
+	Currency string   `json:"currency"`
+	Credits  string   `json:"credits"`
+	At       string   `json:"at,omitempty"`
+	Stale    bool     `json:"stale,omitempty"`
+}
+
+type compareRequest struct {
+	First  creditsRequest `json:"first"`
+	Second creditsRequest `json:"second"`
+}
+
+type compareResponse struct {
+	Result string `json:"result"`
+}
+
+type errorResponse struct {
+	Code  constant.Code `json:"code"`
+	Error string        `json:"error"`
+}
+
+// namespaceHeader names the namespace of a request, the default namespace of
+// the workspace is used without it
+const namespaceHeader = "X-Namespace"
+
+// NewServer exposes the same operations as the text converter over HTTP,
+// with JSON request and response bodies. Every request works inside the
+// namespace named by its X-Namespace header. Only definitions create a
+// namespace, the other requests need it to exist.
+func NewServer(workspace calculator.Workspace) http.Handler {
+	mux := http.NewServeMux()
+	serve := func(pattern string, create bool, handler func(*server, http.ResponseWriter, *http.Request)) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			db, calc, err := workspace(r.Header.Get(namespaceHeader), create)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			handler(&server{db: db, calc: calc}, w, r)
+		})
+	}
+	define := func(pattern string, handler func(*server, http.ResponseWriter, *http.Request)) {
+		serve(pattern, true, handler)
+	}
+	handle := func(pattern string, handler func(*server, http.ResponseWriter, *http.Request)) {
+		serve(pattern, false, handler)
+	}
+
+	define("POST /units", (*server).defineUnit)
+	handle("GET /units", (*server).listUnits)
+	handle("DELETE /units/{unit}", (*server).removeUnit)
+	define("POST /currencies", (*server).defineCurrency)
+	handle("GET /currencies", (*server).listCurrencies)
+	handle("DELETE /currencies/{currency}", (*server).removeCurrency)
+	handle("GET /currencies/{currency}/history", (*server).currencyHistory)
+	handle("POST /convert", (*server).convert)
+	handle("POST /say", (*server).say)
+	handle("POST /credits", (*server).credits)
+	handle("POST /compare", (*server).compare)
+
+	return mux
+}
+
+func (s *server) defineUnit(w http.ResponseWriter, r *http.Request) {
+	var req unitRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	if !parser.IsWord(req.Unit) {
+		badRequest(w, fmt.Sprintf("unit %q is not a single word", req.Unit))
+		return
+	}
+	roman := calculator.CanonicalSymbol(strings.ToUpper(req.Roman))
+	if !calculator.IsNumeralSymbol(roman) {
+		writeError(w, constant.ErrInvalidFormat)
+		return
+	}
+
+	stale, err := s.db.DefineUnit(req.Unit, roman, s.calc.RateFromSymbols)
+	warning, err := constant.SplitWarning(err)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, unitResponse{
+		Unit:    strings.ToLower(req.Unit),
+		Roman:   roman,
+		Warning: warning,
+		Stale:   stale,
+	})
+}
+
+func (s *server) listUnits(w http.ResponseWriter, r *http.Request) {
+	units := make([]unitResponse, 0)
+	for _, unit := range s.db.ListUnits() {
+		roman, err := s.db.GetRomanFromUnit(unit)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		units = append(units, unitResponse{Unit: unit, Roman: roman})
+	}
+
+	writeJSON(w, http.StatusOK, units)
+}
+
+func (s *server) removeUnit(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.db.ForgetUnit(r.PathValue("unit"), s.calc.RateFromSymbols); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) defineCurrency(w http.ResponseWriter, r *http.Request) {
+	var req currencyRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	// Credits are written as in sentences and documents, so never negative
+	// nor with an exponent
+	credits, ok := new(big.Rat).SetString(req.Credits.String())
+	if !ok || !parser.IsNumber(req.Credits.String()) {
+		writeError(w, constant.ErrInvalidCredit)
+		return
+	}
+	if len(req.Units) == 0 {
+		writeError(w, constant.ErrInvalidParse)
+		return
+	}
+	if !parser.IsWord(req.Currency) {
+		badRequest(w, fmt.Sprintf("currency %q is not a single word", req.Currency))
+		return
+	}
+
+	source := database.RateSource{Units: req.Units, Credits: credits}
+	rate, err := s.db.DefineRate(req.Currency, source, s.calc.RateFromSymbols)
+	warning, err := constant.SplitWarning(err)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, currencyResponse{
+		Currency: strings.ToLower(req.Currency),
+		Rate:     rate.RatString(),
+		Warning:  warning,
+	})
+}
+
+func (s *server) listCurrencies(w http.ResponseWriter, r *http.Request) {
+	currencies := make([]currencyResponse, 0)
+	for _, currency := range s.db.ListCurrencies() {
+		rate, err := s.db.GetCreditsFromCurrency(currency)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		currencies = append(currencies, currencyResponse{Currency: currency, Rate: rate.RatString()})
+	}
+
+	writeJSON(w, http.StatusOK, currencies)
+}
+
+func (s *server) removeCurrency(w http.ResponseWriter, r *http.Request) {
+	if err := s.db.RemoveCurrency(r.PathValue("currency")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) currencyHistory(w http.ResponseWriter, r *http.Request) {
+	points, err := s.db.GetRateHistory(r.PathValue("currency"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	history := make([]ratePointResponse, len(points))
+	for i, point := range points {
+		history[i].Rate = point.Credits.RatString()
+		if !point.Time.IsZero() {
+			history[i].Since = point.Time.Format(time.RFC3339)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (s *server) convert(w http.ResponseWriter, r *http.Request) {
+	var req convertRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if len(req.Units) == 0 {
+		badRequest(w, "units is missing")
+		return
+	}
+
+	value, err := s.calc.ConvertUnitsToInt(req.Units)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, convertResponse{Units: req.Units, Value: value})
+}
+
+func (s *server) say(w http.ResponseWriter, r *http.Request) {
+	var req sayRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	units, err := s.calc.ConvertIntToUnits(req.Number, req.System)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, convertResponse{Units: units, Value: req.Number})
+}
+
+func (s *server) credits(w http.ResponseWriter, r *http.Request) {
+	var req creditsQuery
+	if !decode(w, r, &req) {
+		return
+	}
+
+	if len(req.Units) == 0 {
+		badRequest(w, "units is missing")
+		return
+	}
+
+	// The units and the rate are read at once
+	calc := s.calc.With(database.ReadAtOnce(s.db, req.Units, []string{req.Currency}))
+	value, err := calc.ConvertUnitsToInt(req.Units)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// Unlike a value, a quantity of a currency can not be nothing
+	if value <= 0 {
+		writeError(w, &constant.ArithmeticError{
+			Operation: strings.Join(req.Units, " "), Reason: "a quantity of a currency has to be positive",
+		})
+		return
+	}
+
+	if req.At != "" {
+		at, err := time.Parse(time.DateOnly, req.At)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		credits, err := calc.CalculateCreditsCurrencyOn(big.NewRat(int64(value), 1), req.Currency, at)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, creditsResponse{
+			Units:    req.Units,
+			Currency: strings.ToLower(req.Currency),
+			Credits:  credits.FloatString(2),
+			At:       req.At,
+		})
+		return
+	}
+
+	credits, err := calc.CalculateCreditsCurrency(big.NewRat(int64(value), 1), req.Currency)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	source, err := s.db.GetRateSource(req.Currency)
+	writeJSON(w, http.StatusOK, creditsResponse{
+		Units:    req.Units,
+		Currency: strings.ToLower(req.Currency),
+		Credits:  credits.FloatString(2),
+		Stale:    err == nil && source.Stale,
+	})
+}
+
+// compare compares the roman values when no currency is given on either
+// side, and the credits when both sides have one.
+func (s *server) compare(w http.ResponseWriter, r *http.Request) {
+	var req compareRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	switch {
+	case len(req.First.Units) == 0:
+		badRequest(w, "first.units is missing")
+		return
+	case len(req.Second.Units) == 0:
+		badRequest(w, "second.units is missing")
+		return
+	case req.First.Currency == "" && req.Second.Currency != "":
+		badRequest(w, "first.currency is missing, give a currency on both sides or on neither")
+		return
+	case req.First.Currency != "" && req.Second.Currency == "":
+		badRequest(w, "second.currency is missing, give a currency on both sides or on neither")
+		return
+	}
+
+	var (
+		result string
+		err    error
+	)
+	if req.First.Currency == "" {
+		result, err = s.calc.CompareTwoUnits(req.First.Units, req.Second.Units)
+	} else {
+		result, err = s.calc.CompareTwoCurrency(req.First.Units, req.Second.Units, req.First.Currency, req.Second.Currency)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, compareResponse{Result: result})
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		badRequest(w, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// badRequest answers a request whose body can not be read as the operation
+func badRequest(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusBadRequest, errorResponse{Code: constant.CodeParseFailure, Error: message})
+}
+
+func statusFromCode(code constant.Code) int {
+	switch code {
+	case constant.CodeParseFailure, constant.CodeInvalidCredit, constant.CodeInvalidNamespace, constant.CodeInvalidDocument:
+		return http.StatusBadRequest
+	case constant.CodeUnknownUnit, constant.CodeUnknownCurrency, constant.CodeNoRate, constant.CodeUnassignedSymbol, constant.CodeUnknownSystem, constant.CodeUnknownNamespace:
+		return http.StatusNotFound
+	case constant.CodeInvalidNumber, constant.CodeInvalidNumeral, constant.CodeInvalidArithmetic, constant.CodeZeroRate:
+		return http.StatusUnprocessableEntity
+	case constant.CodeConflict:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := constant.CodeOf(err)
+	writeJSON(w, statusFromCode(code), errorResponse{Code: code, Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,582 @@
+package server
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erizkiatama/prospace-assignment/calculator"
+	"github.com/erizkiatama/prospace-assignment/database"
+)
+
+func newTestServer() http.Handler {
+	db := database.NewDatabase()
+	calc := calculator.NewCalculator(db)
+
+	db.DefineUnit("glob", "I", calc.RateFromSymbols)
+	db.DefineUnit("prok", "V", calc.RateFromSymbols)
+	db.DefineUnit("pish", "X", calc.RateFromSymbols)
+	db.DefineUnit("tegj", "L", calc.RateFromSymbols)
+
+	return NewServer(calculator.SingleWorkspace(db, calc))
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "define unit",
+			method:         http.MethodPost,
+			path:           "/units",
+			body:           `{"unit":"Zorg","roman":"c"}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"unit":"zorg","roman":"C"}`,
+		},
+		{
+			name:           "define unit with invalid roman",
+			method:         http.MethodPost,
+			path:           "/units",
+			body:           `{"unit":"zorg","roman":"Z"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"code":"invalid_number","error":"requested number is in invalid format"}`,
+		},
+		{
+			name:           "define unit of several words",
+			method:         http.MethodPost,
+			path:           "/units",
+			body:           `{"unit":"glob prok","roman":"I"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"unit \"glob prok\" is not a single word"}`,
+		},
+		{
+			name:           "define unit without name",
+			method:         http.MethodPost,
+			path:           "/units",
+			body:           `{"roman":"I"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"unit \"\" is not a single word"}`,
+		},
+		{
+			name:           "define unit with mayan digit",
+			method:         http.MethodPost,
+			path:           "/units",
+			body:           `{"unit":"hun","roman":"1"}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"unit":"hun","roman":"1"}`,
+		},
+		{
+			name:           "define currency",
+			method:         http.MethodPost,
+			path:           "/currencies",
+			body:           `{"units":["pish","pish"],"currency":"Iron","credits":3910}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"currency":"iron","rate":"391/2"}`,
+		},
+		{
+			name:           "define currency without credits",
+			method:         http.MethodPost,
+			path:           "/currencies",
+			body:           `{"units":["pish"],"currency":"Iron"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"invalid_credit","error":"credits is not a number"}`,
+		},
+		{
+			name:           "define currency with negative credits",
+			method:         http.MethodPost,
+			path:           "/currencies",
+			body:           `{"units":["pish"],"currency":"Iron","credits":-34}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"invalid_credit","error":"credits is not a number"}`,
+		},
+		{
+			name:           "define currency with an exponent",
+			method:         http.MethodPost,
+			path:           "/currencies",
+			body:           `{"units":["pish"],"currency":"Iron","credits":1e400}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"invalid_credit","error":"credits is not a number"}`,
+		},
+		{
+			name:           "define currency without units",
+			method:         http.MethodPost,
+			path:           "/currencies",
+			body:           `{"units":[],"currency":"Iron","credits":34}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"i have no idea what are you talking about"}`,
+		},
+		{
+			name:           "define currency with punctuation",
+			method:         http.MethodPost,
+			path:           "/currencies",
+			body:           `{"units":["pish"],"currency":"Iron?","credits":34}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"currency \"Iron?\" is not a single word"}`,
+		},
+		{
+			name:           "define currency with unknown unit",
+			method:         http.MethodPost,
+			path:           "/currencies",
+			body:           `{"units":["blah"],"currency":"Iron","credits":10}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"unknown_unit","error":"blah unit is not defined in the intergalactic database"}`,
+		},
+		{
+			name:           "convert units",
+			method:         http.MethodPost,
+			path:           "/convert",
+			body:           `{"units":["pish","tegj","glob","glob"]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"units":["pish","tegj","glob","glob"],"value":42}`,
+		},
+		{
+			name:           "convert no units",
+			method:         http.MethodPost,
+			path:           "/convert",
+			body:           `{"units":[]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"units is missing"}`,
+		},
+		{
+			name:           "convert invalid numeral",
+			method:         http.MethodPost,
+			path:           "/convert",
+			body:           `{"units":["glob","glob","glob","glob"]}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"code":"invalid_numeral","error":"requested number is in invalid format: IIII is invalid at symbol 4, I is repeated more than 3 times, write it as IV"}`,
+		},
+		{
+			name:           "say number",
+			method:         http.MethodPost,
+			path:           "/say",
+			body:           `{"number":14}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"units":["pish","glob","prok"],"value":14}`,
+		},
+		{
+			name:           "say number in mayan",
+			method:         http.MethodPost,
+			path:           "/say",
+			body:           `{"number":21,"system":"mayan"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"units":["hun","hun"],"value":21}`,
+		},
+		{
+			name:           "say number in unknown system",
+			method:         http.MethodPost,
+			path:           "/say",
+			body:           `{"number":21,"system":"babylonian"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"unknown_numeral_system","error":"babylonian numeral system is not defined in the intergalactic database"}`,
+		},
+		{
+			name:           "say number without unit",
+			method:         http.MethodPost,
+			path:           "/say",
+			body:           `{"number":1000}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"unassigned_symbol","error":"M numeral symbol has no unit assigned in the intergalactic database"}`,
+		},
+		{
+			name:           "credits",
+			method:         http.MethodPost,
+			path:           "/credits",
+			body:           `{"units":["pish","tegj","glob"],"currency":"Iron"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"units":["pish","tegj","glob"],"currency":"iron","credits":"8015.50"}`,
+		},
+		{
+			name:           "define unit with mayan zero",
+			method:         http.MethodPost,
+			path:           "/units",
+			body:           `{"unit":"kal","roman":"0"}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"unit":"kal","roman":"0"}`,
+		},
+		{
+			name:           "credits of nothing",
+			method:         http.MethodPost,
+			path:           "/credits",
+			body:           `{"units":["kal"],"currency":"Iron"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"code":"invalid_arithmetic","error":"kal can not be computed, a quantity of a currency has to be positive"}`,
+		},
+		{
+			name:           "credits without units",
+			method:         http.MethodPost,
+			path:           "/credits",
+			body:           `{"currency":"Iron"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"units is missing"}`,
+		},
+		{
+			name:           "credits with unknown currency",
+			method:         http.MethodPost,
+			path:           "/credits",
+			body:           `{"units":["glob"],"currency":"Wood"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"unknown_currency","error":"wood currency is not defined in the intergalactic database"}`,
+		},
+		{
+			name:           "compare units",
+			method:         http.MethodPost,
+			path:           "/compare",
+			body:           `{"first":{"units":["glob","prok"]},"second":{"units":["pish"]}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":"smaller than"}`,
+		},
+		{
+			name:           "compare credits",
+			method:         http.MethodPost,
+			path:           "/compare",
+			body:           `{"first":{"units":["pish","pish"],"currency":"iron"},"second":{"units":["glob"],"currency":"iron"}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":"has more credits than"}`,
+		},
+		{
+			name:           "compare credits with one currency",
+			method:         http.MethodPost,
+			path:           "/compare",
+			body:           `{"first":{"units":["pish"],"currency":"iron"},"second":{"units":["glob"]}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"second.currency is missing, give a currency on both sides or on neither"}`,
+		},
+		{
+			name:           "compare nothing",
+			method:         http.MethodPost,
+			path:           "/compare",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"first.units is missing"}`,
+		},
+		{
+			name:           "compare units without first units",
+			method:         http.MethodPost,
+			path:           "/compare",
+			body:           `{"first":{"units":[]},"second":{"units":["glob"]}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"first.units is missing"}`,
+		},
+		{
+			name:           "compare units without second units",
+			method:         http.MethodPost,
+			path:           "/compare",
+			body:           `{"first":{"units":["glob"]},"second":{}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"second.units is missing"}`,
+		},
+		{
+			name:           "compare credits without first units",
+			method:         http.MethodPost,
+			path:           "/compare",
+			body:           `{"first":{"units":[],"currency":"iron"},"second":{"units":["glob"],"currency":"iron"}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"first.units is missing"}`,
+		},
+		{
+			name:           "redefine unit",
+			method:         http.MethodPost,
+			path:           "/units",
+			body:           `{"unit":"zorg","roman":"D"}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"unit":"zorg","roman":"D","warning":"zorg unit is already assigned to C"}`,
+		},
+		{
+			name:           "list units",
+			method:         http.MethodGet,
+			path:           "/units",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"unit":"glob","roman":"I"},{"unit":"hun","roman":"1"},{"unit":"kal","roman":"0"},{"unit":"pish","roman":"X"},{"unit":"prok","roman":"V"},{"unit":"tegj","roman":"L"},{"unit":"zorg","roman":"D"}]`,
+		},
+		{
+			name:           "remove unit",
+			method:         http.MethodDelete,
+			path:           "/units/Zorg",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "remove unknown unit",
+			method:         http.MethodDelete,
+			path:           "/units/zorg",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"unknown_unit","error":"zorg unit is not defined in the intergalactic database"}`,
+		},
+		{
+			name:           "list currencies",
+			method:         http.MethodGet,
+			path:           "/currencies",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"currency":"iron","rate":"391/2"}]`,
+		},
+		{
+			name:           "remove unit used by a rate",
+			method:         http.MethodDelete,
+			path:           "/units/pish",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "credits with a stale rate",
+			method:         http.MethodPost,
+			path:           "/credits",
+			body:           `{"units":["glob"],"currency":"iron"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"units":["glob"],"currency":"iron","credits":"195.50","stale":true}`,
+		},
+		{
+			name:           "remove currency",
+			method:         http.MethodDelete,
+			path:           "/currencies/iron",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "malformed body",
+			method:         http.MethodPost,
+			path:           "/convert",
+			body:           `{"units":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"parse_failure","error":"unexpected EOF"}`,
+		},
+		{
+			name:           "wrong method",
+			method:         http.MethodGet,
+			path:           "/convert",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	// The requests share one server, later requests rely on earlier definitions
+	handler := newTestServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.expectedStatus)
+			}
+			if tt.expectedBody != "" && strings.TrimSpace(rec.Body.String()) != tt.expectedBody {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestServerRejectsConflicts(t *testing.T) {
+	db := database.NewDatabase(database.WithPolicy(database.PolicyReject))
+	calc := calculator.NewCalculator(db)
+	db.DefineUnit("glob", "I", calc.RateFromSymbols)
+	handler := NewServer(calculator.SingleWorkspace(db, calc))
+
+	req := httptest.NewRequest(http.MethodPost, "/units", strings.NewReader(`{"unit":"prok","roman":"I"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	expectedBody := `{"code":"conflict","error":"I symbol is already assigned to glob"}`
+	if rec.Code != http.StatusConflict || strings.TrimSpace(rec.Body.String()) != expectedBody {
+		t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body.String(), http.StatusConflict, expectedBody)
+	}
+}
+
+// fixedClock always tells the same time
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestServerRateHistory(t *testing.T) {
+	db := database.NewDatabase(
+		database.WithClock(fixedClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}),
+		database.WithPolicy(database.PolicyOverwrite),
+	)
+	calc := calculator.NewCalculator(db)
+	db.DefineUnit("glob", "I", calc.RateFromSymbols)
+	db.DefineRate("silver", database.RateSource{Units: []string{"glob"}, Credits: big.NewRat(17, 1)}, calc.RateFromSymbols)
+	handler := NewServer(calculator.SingleWorkspace(db, calc))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "credits on a day",
+			method:         http.MethodPost,
+			path:           "/credits",
+			body:           `{"units":["glob"],"currency":"silver","at":"2026-01-01"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"units":["glob"],"currency":"silver","credits":"17.00","at":"2026-01-01"}`,
+		},
+		{
+			name:           "credits before the first rate",
+			method:         http.MethodPost,
+			path:           "/credits",
+			body:           `{"units":["glob"],"currency":"silver","at":"2025-12-31"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"no_rate","error":"silver currency has no rate yet on 2025-12-31"}`,
+		},
+		{
+			name:           "credits on an invalid day",
+			method:         http.MethodPost,
+			path:           "/credits",
+			body:           `{"units":["glob"],"currency":"silver","at":"tomorrow"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "history",
+			method:         http.MethodGet,
+			path:           "/currencies/Silver/history",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"since":"2026-01-01T12:00:00Z","rate":"17"}]`,
+		},
+		{
+			name:           "history of unknown currency",
+			method:         http.MethodGet,
+			path:           "/currencies/gold/history",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"unknown_currency","error":"gold currency is not defined in the intergalactic database"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.expectedStatus)
+			}
+			if tt.expectedBody != "" && strings.TrimSpace(rec.Body.String()) != tt.expectedBody {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestServerConcurrentRequests(t *testing.T) {
+	db := database.NewDatabase(database.WithPolicy(database.PolicyOverwrite))
+	handler := NewServer(calculator.SingleWorkspace(db, calculator.NewCalculator(db)))
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/units", `{"unit":"glob","roman":"I"}`},
+		{http.MethodPost, "/units", `{"unit":"prok","roman":"V"}`},
+		{http.MethodPost, "/currencies", `{"units":["glob","glob"],"currency":"silver","credits":34}`},
+		{http.MethodPost, "/currencies", `{"units":["prok"],"currency":"gold","credits":100}`},
+		{http.MethodPost, "/credits", `{"units":["glob"],"currency":"silver"}`},
+		{http.MethodPost, "/compare", `{"first":{"units":["glob"],"currency":"silver"},"second":{"units":["prok"],"currency":"gold"}}`},
+		{http.MethodGet, "/units", ""},
+		{http.MethodGet, "/currencies", ""},
+		{http.MethodGet, "/currencies/silver/history", ""},
+		{http.MethodDelete, "/units/glob", ""},
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				r := requests[(w+i)%len(requests)]
+				req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+
+				// Answers depend on the order of the requests, but never fail
+				if rec.Code >= http.StatusInternalServerError {
+					t.Errorf("%s %s = %d %s", r.method, r.path, rec.Code, rec.Body.String())
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func TestServerNamespaces(t *testing.T) {
+	namespaces := database.NewNamespaces(func(name string) (database.Database, error) {
+		return database.NewDatabase(), nil
+	}, nil)
+	handler := NewServer(calculator.NewWorkspace(namespaces, database.DefaultNamespace))
+
+	tests := []struct {
+		name           string
+		namespace      string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"define in the default namespace", "", http.MethodPost, "/units", `{"unit":"glob","roman":"I"}`, http.StatusCreated, `{"unit":"glob","roman":"I"}`},
+		{"define in team-b", "team-b", http.MethodPost, "/units", `{"unit":"glob","roman":"X"}`, http.StatusCreated, `{"unit":"glob","roman":"X"}`},
+		{"convert in the default namespace", "default", http.MethodPost, "/convert", `{"units":["glob","glob"]}`, http.StatusOK, `{"units":["glob","glob"],"value":2}`},
+		{"convert in team-b", "Team-B", http.MethodPost, "/convert", `{"units":["glob","glob"]}`, http.StatusOK, `{"units":["glob","glob"],"value":20}`},
+		{"list an unknown namespace", "team-c", http.MethodGet, "/units", "", http.StatusNotFound, `{"code":"unknown_namespace","error":"team-c namespace is not defined in the intergalactic database"}`},
+		{"convert in an unknown namespace", "team-c", http.MethodPost, "/convert", `{"units":["glob"]}`, http.StatusNotFound, `{"code":"unknown_namespace","error":"team-c namespace is not defined in the intergalactic database"}`},
+		{"define in team-c", "team-c", http.MethodPost, "/units", `{"unit":"glob","roman":"V"}`, http.StatusCreated, `{"unit":"glob","roman":"V"}`},
+		{"list team-c once defined", "team-c", http.MethodGet, "/units", "", http.StatusOK, `[{"unit":"glob","roman":"V"}]`},
+		{"invalid namespace", "team c", http.MethodGet, "/units", "", http.StatusBadRequest, `{"code":"invalid_namespace","error":"\"team c\" is not a valid namespace, use up to 32 letters, digits, - and _"}`},
+		{"too long namespace", strings.Repeat("a", 33), http.MethodPost, "/units", `{"unit":"glob","roman":"I"}`, http.StatusBadRequest, `{"code":"invalid_namespace","error":"\"` + strings.Repeat("a", 33) + `\" is not a valid namespace, use up to 32 letters, digits, - and _"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.namespace != "" {
+				req.Header.Set("X-Namespace", tt.namespace)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.expectedStatus)
+			}
+			if strings.TrimSpace(rec.Body.String()) != tt.expectedBody {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+
+	// Read-only requests never open a namespace
+	if names := namespaces.List(); !reflect.DeepEqual(names, []string{"default", "team-b", "team-c"}) {
+		t.Errorf("namespaces = %v, want [default team-b team-c]", names)
+	}
+}
+
+func TestServerUnknownNamespace(t *testing.T) {
+	db := database.NewDatabase()
+	handler := NewServer(calculator.SingleWorkspace(db, calculator.NewCalculator(db)))
+
+	req := httptest.NewRequest(http.MethodGet, "/units", nil)
+	req.Header.Set("X-Namespace", "team-a")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	expectedBody := `{"code":"unknown_namespace","error":"team-a namespace is not defined in the intergalactic database"}`
+	if rec.Code != http.StatusNotFound || strings.TrimSpace(rec.Body.String()) != expectedBody {
+		t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body.String(), http.StatusNotFound, expectedBody)
+	}
+}